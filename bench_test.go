@@ -0,0 +1,133 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package lubm_test
+
+import (
+	"flag"
+	"sync"
+	"testing"
+
+	"github.com/kshard/lubm"
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/store/ephemeral"
+)
+
+//
+// go test -run=^$ -bench=. -benchmem -count=10 -args -lubm.universities=1 | tee new.txt
+// benchstat old.txt new.txt
+//
+
+var (
+	seed         = flag.Int64("lubm.seed", 1683234740, "seed of random generator")
+	universities = flag.Int("lubm.universities", 1, "number of universities")
+)
+
+// knowledge statements of universities [from, to) of the dataset with n
+// universities
+func statements(n, from, to int) (*lubm.Statements, error) {
+	bags := &lubm.Statements{}
+	ds := lubm.NewDataSet(*seed, n, nil)
+	for i := from; i < to; i++ {
+		if err := ds.Visit(i, bags); err != nil {
+			return nil, err
+		}
+	}
+	return bags, nil
+}
+
+// Fixture is built once and shared by benchmarks, the error is kept so that
+// every benchmark fails if the fixture is not built.
+var (
+	fixtureOnce  sync.Once
+	fixtureBags  []spock.Bag
+	fixtureSize  int
+	fixtureStore *ephemeral.Store
+	fixtureErr   error
+)
+
+func fixture(b *testing.B) {
+	fixtureOnce.Do(func() {
+		var bags *lubm.Statements
+		bags, fixtureErr = statements(*universities, 0, *universities)
+		if fixtureErr != nil {
+			return
+		}
+
+		fixtureStore = ephemeral.New()
+		fixtureBags, fixtureSize = bags.Bags, bags.Len()
+		for _, bag := range fixtureBags {
+			ephemeral.Add(fixtureStore, bag)
+		}
+	})
+
+	if fixtureErr != nil {
+		b.Fatal(fixtureErr)
+	}
+}
+
+func BenchmarkGenerate(b *testing.B) {
+	b.ReportAllocs()
+
+	size := 0
+	for i := 0; i < b.N; i++ {
+		bags, err := statements(*universities, 0, *universities)
+		if err != nil {
+			b.Fatal(err)
+		}
+		size = bags.Len()
+	}
+
+	b.ReportMetric(float64(size), "triples/op")
+}
+
+func BenchmarkLoad(b *testing.B) {
+	fixture(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		store := ephemeral.New()
+		for _, bag := range fixtureBags {
+			ephemeral.Add(store, bag)
+		}
+	}
+
+	b.ReportMetric(float64(fixtureSize), "triples/op")
+}
+
+func benchmarkQuery(b *testing.B, id int) {
+	fixture(b)
+	q, err := lubm.Query(id)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	size := 0
+	for i := 0; i < b.N; i++ {
+		result, err := lubm.Eval(fixtureStore, q)
+		if err != nil {
+			b.Fatal(err)
+		}
+		size = result.Len()
+	}
+
+	b.ReportMetric(float64(size), "results/op")
+}
+
+func BenchmarkQuery1(b *testing.B) { benchmarkQuery(b, 1) }
+func BenchmarkQuery2(b *testing.B) { benchmarkQuery(b, 2) }
+func BenchmarkQuery3(b *testing.B) { benchmarkQuery(b, 3) }
+func BenchmarkQuery4(b *testing.B) { benchmarkQuery(b, 4) }
+func BenchmarkQuery5(b *testing.B) { benchmarkQuery(b, 5) }
+func BenchmarkQuery6(b *testing.B) { benchmarkQuery(b, 6) }
+func BenchmarkQuery7(b *testing.B) { benchmarkQuery(b, 7) }
+func BenchmarkQuery8(b *testing.B) { benchmarkQuery(b, 8) }
+func BenchmarkQuery9(b *testing.B) { benchmarkQuery(b, 9) }
@@ -9,91 +9,798 @@
 package main
 
 import (
-	"bytes"
+	"context"
+	"flag"
 	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/kshard/lubm"
-	"github.com/kshard/lubm/internal/adapter"
-	"github.com/kshard/sigma"
-	"github.com/kshard/sigma/asm"
-	"github.com/kshard/sigma/lang"
+	"github.com/kshard/lubm/encoding/snapshot"
+	"github.com/kshard/lubm/export/datalog"
+	"github.com/kshard/lubm/export/dictionary"
+	"github.com/kshard/lubm/export/graph"
+	"github.com/kshard/lubm/export/relational"
+	"github.com/kshard/lubm/internal/bench"
+	"github.com/kshard/lubm/internal/endpoint"
+	"github.com/kshard/lubm/loader"
+	"github.com/kshard/lubm/output"
 	"github.com/kshard/spock"
 	"github.com/kshard/spock/store/ephemeral"
 )
 
 // TODO: use cobra
 
+var (
+	seed       = flag.Int64("seed", 1683234740, "seed of random generator")
+	n          = flag.Int("n", 1, "number of universities")
+	triples    = flag.String("triples", "", "approximate number of triples, e.g. 10M, the number of universities is derived from it")
+	withParams = flag.Bool("params", false, "draw distinct query parameters from the dataset for every iteration")
+	warmup     = flag.Int("warmup", 1, "number of warm-up iterations per query")
+	iterations = flag.Int("iterations", 10, "number of measured iterations per query")
+	timeout    = flag.Duration("timeout", time.Minute, "timeout of single query execution")
+	jsonFile   = flag.String("json", "", "write reports to JSON file")
+	csvFile    = flag.String("csv", "", "write reports to CSV file")
+	clients    = flag.Int("clients", 0, "number of concurrent clients running query mix (0 runs queries sequentially)")
+	duration   = flag.Duration("duration", 0, "duration of query mix run, iterations per client are used if not defined; duration of the final phase of mixed load, 10s if not defined")
+	mix        = flag.String("mix", "", "weights of queries in the mix, e.g. Query1=10,Query2=1")
+	ingest     = flag.Int("ingest", 0, "number of universities ingested while clients run the query suite, queries and writes are serialized by lock")
+	addr       = flag.String("addr", "localhost:8080", "listen address of serve command")
+	logQueries = flag.Bool("log", false, "log queries served by endpoint")
+	load       = flag.String("load", "", "comma separated list of N-Triples, JSON-LD, snapshot or dictionary-encoded files (optionally gzip), or manifest of the dataset, loaded instead of generating dataset")
+	baseline   = flag.String("baseline", "", "compare results with baseline JSON report of the same dataset")
+	outputFile = flag.String("o", "", "write dataset to N-Triples (.nt), JSON-LD (.jsonld), binary snapshot (.snap) or dictionary-encoded (.dict) file, compressed if name ends with .gz")
+	partition  = flag.String("partition", "", "generate only universities of partition i out of N, e.g. -partition 0/4")
+	resume     = flag.Bool("resume", false, "resume generation of the dataset sharded by university from its checkpoint")
+	quiet      = flag.Bool("quiet", false, "do not report progress of generation and loading")
+	shard      = flag.String("shard", "", "split output into shards per university or by number of triples, e.g. -shard university or -shard 1000000")
+	changes    = flag.Int("changes", 1000, "number of changes applied by updates command")
+	batch      = flag.Int("batch", 100, "number of changes applied by updates command before queries are checked")
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [generate | serve | estimate | updates | export graph|sql|datalog|dictionary]\n\n", os.Args[0])
+	fmt.Fprintf(flag.CommandLine.Output(), "Generates (or loads) dataset and runs query benchmark. The generate command\nonly writes the dataset to file, serve command exposes SPARQL 1.1 Protocol\nendpoint at /sparql instead. The estimate command predicts size of the dataset\nwithout generating it. The updates command applies changes to the dataset\nand checks answers of queries after every batch of changes. The export\ncommand writes entities of the dataset into directory defined by -o, as\nproperty graph, relational tables, Datalog facts or dictionary-encoded\ntriples.\n\n")
+	flag.PrintDefaults()
+}
+
 func main() {
-	n := 1
+	flag.Usage = usage
+	flag.Parse()
+
+	command := flag.Arg(0)
+	switch command {
+	case "", "serve", "generate", "estimate", "updates", "export":
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	n := *n
+	if *triples != "" {
+		size, err := parseTriples(*triples)
+		if err != nil {
+			panic(err)
+		}
+		n = lubm.UniversitiesFor(size)
+	}
+
+	if command == "estimate" {
+		estimate(n)
+		return
+	}
+
+	if command == "updates" {
+		update(n)
+		return
+	}
+
 	store := ephemeral.New()
 
 	//
 	// Intake
 	//
+	from, to, err := parsePartition(*partition, n)
+	if err != nil {
+		panic(err)
+	}
+
+	if command == "export" {
+		export(flag.Arg(1), n+*ingest, from, to)
+		return
+	}
+
+	manifest := lubm.NewManifest(*seed, from, to, n+*ingest)
+	manifest.Partition = *partition
+	files := []string{}
+	if *load != "" {
+		manifest, files = loaded(strings.Split(*load, ","))
+	}
+
+	var file *output.Writer
+	if *outputFile != "" {
+		sharding, budget, err := parseShard(*shard)
+		if err != nil {
+			panic(err)
+		}
+		if sharding == output.University && *load != "" {
+			panic("sharding by university requires generation of the dataset")
+		}
+
+		file, err = output.New(output.Config{
+			Path:     *outputFile,
+			Sharding: sharding,
+			Budget:   budget,
+			Header: snapshot.Header{
+				Seed:            manifest.Seed,
+				From:            manifest.From,
+				To:              manifest.To,
+				MaxUniversityID: manifest.MaxUniversityID,
+			},
+		})
+		if err != nil {
+			panic(err)
+		}
+	}
+
 	size := 0
+	digest := lubm.Digest{}
+
+	// universities sharded into files are checkpointed while generated
+	var checkpoint *output.Checkpoint
+	if command == "generate" && file != nil && *shard == "university" {
+		checkpoint = &output.Checkpoint{
+			Seed:            manifest.Seed,
+			From:            manifest.From,
+			To:              manifest.To,
+			MaxUniversityID: manifest.MaxUniversityID,
+			Next:            manifest.From,
+		}
+	}
+
+	if *resume {
+		if checkpoint == nil {
+			panic("resume requires generate command with -o and -shard university")
+		}
+
+		c, err := output.ReadCheckpoint(output.CheckpointOf(*outputFile))
+		if err != nil {
+			panic(err)
+		}
+		if c.Seed != checkpoint.Seed || c.From != checkpoint.From || c.To != checkpoint.To || c.MaxUniversityID != checkpoint.MaxUniversityID {
+			panic(fmt.Errorf("checkpoint of seed %d, universities [%d, %d) of %d does not match the dataset", c.Seed, c.From, c.To, c.MaxUniversityID))
+		}
+		if err := file.Resume(c); err != nil {
+			panic(err)
+		}
+
+		digest, err = lubm.ParseDigest(c.Digest)
+		if err != nil {
+			panic(err)
+		}
+
+		checkpoint, size, from = c, c.Triples, c.Next
+		fmt.Printf("==> resume from university %d, %d triples\n", c.Next, c.Triples)
+	}
+
 	ch := make(chan spock.Bag, 0)
+	universities := make(chan int)
+	done := make(chan struct{})
 	go func() {
-		for x := range ch {
-			ephemeral.Add(store, x)
-			size = size + len(x)
+		defer close(done)
+		for {
+			select {
+			case x, ok := <-ch:
+				if !ok {
+					return
+				}
+				digest.Add(x)
+				if command != "generate" {
+					ephemeral.Add(store, x)
+				}
+				if file != nil {
+					if err := file.Write(x); err != nil {
+						panic(err)
+					}
+				}
+				size = size + len(x)
+			case university := <-universities:
+				if file != nil {
+					if err := file.Done(university); err != nil {
+						panic(err)
+					}
+				}
+				if checkpoint != nil {
+					checkpoint.Next = university + 1
+					checkpoint.Files = file.Files()
+					checkpoint.Triples = size
+					checkpoint.Digest = digest.String()
+					if err := checkpoint.WriteFile(output.CheckpointOf(*outputFile)); err != nil {
+						panic(err)
+					}
+				}
+			}
 		}
 	}()
 
 	t := time.Now()
-	ds := lubm.NewDataSet(1683234740, n, ch)
-	for i := 0; i < n; i++ {
-		if err := ds.Generate(i); err != nil {
+	completed, interrupted := to, false
+
+	// the university or the file being loaded is completed on interrupt
+	ctx, stop := interruptible()
+	if *load != "" {
+		ld := loader.New(ch, func(p loader.Progress) {
+			if !*quiet {
+				fmt.Printf("==> %s: %d triples (%.0f/s), %s of %s, ETA %v\n",
+					p.Path, p.Triples, p.Rate(), bytesOf(p.Bytes), bytesOf(p.Size), p.ETA().Round(time.Second))
+			}
+		})
+		sources, _, err := ld.LoadFilesContext(ctx, files, runtime.GOMAXPROCS(0))
+		if err != nil && err != ctx.Err() {
 			panic(err)
 		}
-		fmt.Printf("==> university %d: %d in %v\n", i, size, time.Since(t))
+		if len(sources) < len(files) {
+			interrupted = true
+			manifest.Sources = sources
+		}
+	} else {
+		ds := lubm.NewDataSet(*seed, n+*ingest, ch)
+		ds.OnProgress(to-from, func(p lubm.Progress) {
+			written := ""
+			if file != nil {
+				written = ", " + bytesOf(file.Bytes()) + " written"
+			}
+			progress(p, written)
+		})
+
+		for i := from; i < to && ctx.Err() == nil; i++ {
+			if err := ds.Generate(i); err != nil {
+				panic(err)
+			}
+			universities <- i
+			completed = i + 1
+		}
+		interrupted = completed < to
 	}
-	fmt.Printf("==> loaded %d in %v\n", size, time.Since(t))
+	stop()
 
 	close(ch)
+	<-done
+	fmt.Printf("==> loaded %d in %v\n", size, time.Since(t))
+
+	manifest.Elapsed = time.Since(t)
+	if *load != "" && !interrupted {
+		verify(manifest, size, digest)
+	}
+	manifest.Triples = size
+	manifest.Digest = digest.String()
+
+	switch {
+	case interrupted && *load != "":
+		fmt.Fprintf(os.Stderr, "==> interrupted, %d of %d files are complete\n", len(manifest.Sources), len(files))
+		manifest.Partial = true
+	case interrupted:
+		fmt.Fprintf(os.Stderr, "==> interrupted, universities [%d, %d) are complete\n", manifest.From, completed)
+		manifest.To = completed
+		manifest.Partial = true
+	}
+
+	if file != nil {
+		if err := file.Close(); err != nil {
+			panic(err)
+		}
+
+		out := *manifest
+		out.Formats = []string{file.Format().String()}
+		out.Files = file.Files()
+		if err := out.WriteFile(lubm.ManifestOf(*outputFile)); err != nil {
+			panic(err)
+		}
+
+		if checkpoint != nil && !interrupted {
+			if err := os.Remove(output.CheckpointOf(*outputFile)); err != nil && !os.IsNotExist(err) {
+				panic(err)
+			}
+		}
+	}
+
+	if interrupted {
+		os.Exit(130)
+	}
+
+	if command == "generate" {
+		return
+	}
+
+	if command == "serve" {
+		serve(store)
+		return
+	}
 
 	//
 	// Benchmark
 	//
-	qs := []string{
-		lubm.Query1(),
-		lubm.Query2(),
-		lubm.Query3(),
-		lubm.Query4(),
-		lubm.Query5(),
-		lubm.Query6(),
-		lubm.Query7(),
-		lubm.Query8(),
-		lubm.Query9(),
+	var params *lubm.Params
+	if *withParams {
+		var err error
+		params, err = lubm.NewParams(*seed, store)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if *ingest > 0 {
+		mixed(store, params, manifest)
+		return
+	}
+
+	if *clients > 0 {
+		throughput(store, params, manifest)
+		return
+	}
+
+	config := bench.Config{
+		Warmup:     *warmup,
+		Iterations: *iterations,
+		Timeout:    *timeout,
+	}
+
+	reports := bench.Run(store, bench.Cases(params), config)
+	if err := bench.WriteTable(os.Stdout, reports); err != nil {
+		panic(err)
+	}
+
+	results := &bench.Results{Dataset: manifest, Reports: reports}
+	if *jsonFile != "" {
+		if err := writeFile(*jsonFile, func(w io.Writer) error { return bench.WriteJSON(w, results) }); err != nil {
+			panic(err)
+		}
+	}
+
+	if *baseline != "" {
+		base, err := bench.ReadResults(*baseline)
+		if err != nil {
+			panic(err)
+		}
+
+		deltas, err := bench.Compare(base, results)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to compare with %s: %s\n", *baseline, err)
+			os.Exit(1)
+		}
+
+		if err := bench.WriteCompare(os.Stdout, deltas); err != nil {
+			panic(err)
+		}
 	}
 
-	for i, qx := range qs {
-		t = time.Now()
-		if q, err := query(store, qx); err == nil {
-			fmt.Printf("==> query #%d %8.d in %v\n", i+1, q, time.Since(t))
-		} else {
-			fmt.Printf("==> query #%d failed %s", i+1, err)
+	if *csvFile != "" {
+		if err := writeFile(*csvFile, func(w io.Writer) error { return bench.WriteCSV(w, reports) }); err != nil {
+			panic(err)
 		}
 	}
 }
 
-func query(store *ephemeral.Store, q string) (int, error) {
-	buf := bytes.NewBuffer([]byte(q))
+// runs query mix from concurrent clients
+func throughput(store *ephemeral.Store, params *lubm.Params, manifest *lubm.Manifest) {
+	weights, err := parseMix(*mix)
+	if err != nil {
+		panic(err)
+	}
+
+	config := bench.ThroughputConfig{
+		Seed:     *seed,
+		Clients:  *clients,
+		Duration: *duration,
+		Timeout:  *timeout,
+	}
+	if config.Duration == 0 {
+		config.Iterations = *iterations
+	}
 
-	parser := lang.NewParser(buf)
-	rules, err := parser.Parse()
+	cases, err := bench.Mix(bench.Cases(params), weights)
 	if err != nil {
-		return 0, err
+		panic(err)
 	}
 
-	machine, err := sigma.New("q", rules)
+	report, err := bench.Throughput(store, cases, config)
 	if err != nil {
-		return 0, err
+		panic(err)
+	}
+	report.Dataset = manifest
+	if err := bench.WriteThroughput(os.Stdout, report); err != nil {
+		panic(err)
+	}
+
+	if *jsonFile != "" {
+		if err := writeFile(*jsonFile, func(w io.Writer) error { return bench.WriteJSON(w, report) }); err != nil {
+			panic(err)
+		}
 	}
 
-	ctx := asm.NewContext().Add("f", adapter.NewStream(store))
-	reader := sigma.Stream(ctx, machine)
+	if *csvFile != "" {
+		if err := writeFile(*csvFile, func(w io.Writer) error { return bench.WriteCSV(w, report.PerQuery) }); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// runs query suite while new universities are ingested
+func mixed(store *ephemeral.Store, params *lubm.Params, manifest *lubm.Manifest) {
+	config := bench.MixedConfig{
+		Seed:            *seed,
+		Clients:         *clients,
+		From:            *n,
+		To:              *n + *ingest,
+		MaxUniversityID: *n + *ingest,
+		Timeout:         *timeout,
+		Hold:            *duration,
+	}
+	if config.Clients == 0 {
+		config.Clients = 1
+	}
+	if config.Hold == 0 {
+		config.Hold = 10 * time.Second
+	}
+
+	report, err := bench.Mixed(store, bench.Cases(params), config)
+	if err != nil {
+		panic(err)
+	}
+	report.Dataset = manifest
+
+	if err := bench.WriteMixed(os.Stdout, report); err != nil {
+		panic(err)
+	}
+
+	if *jsonFile != "" {
+		if err := writeFile(*jsonFile, func(w io.Writer) error { return bench.WriteJSON(w, report) }); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// applies changes to universities [0, n) and checks answers of queries
+func update(n int) {
+	config := bench.UpdatesConfig{
+		Seed:            *seed,
+		Universities:    n,
+		MaxUniversityID: n,
+		Changes:         *changes,
+		Batch:           *batch,
+		Timeout:         *timeout,
+	}
+
+	report, err := bench.Updates(config)
+	if err != nil {
+		panic(err)
+	}
+	report.Dataset = lubm.NewManifest(*seed, 0, n, n)
+
+	if err := bench.WriteUpdates(os.Stdout, report); err != nil {
+		panic(err)
+	}
+
+	if *jsonFile != "" {
+		if err := writeFile(*jsonFile, func(w io.Writer) error { return bench.WriteJSON(w, report) }); err != nil {
+			panic(err)
+		}
+	}
+
+	if len(report.Failures) > 0 {
+		os.Exit(1)
+	}
+}
+
+// manifest of loaded files and paths of the files. Either manifest or
+// list of files is loaded. Parameters of generation are known only if all
+// files belong to the same manifest.
+func loaded(paths []string) (*lubm.Manifest, []string) {
+	if len(paths) == 1 && strings.HasSuffix(paths[0], ".manifest.json") {
+		manifest, err := lubm.ReadManifest(paths[0])
+		if err != nil {
+			panic(err)
+		}
+
+		files := make([]string, len(manifest.Files))
+		for i, file := range manifest.Files {
+			files[i] = filepath.Join(filepath.Dir(paths[0]), file.Path)
+		}
+
+		manifest.Modules = lubm.Modules()
+		manifest.Started = time.Now().UTC()
+		return manifest, files
+	}
+
+	manifest := &lubm.Manifest{Modules: lubm.Modules(), Started: time.Now().UTC()}
+
+	origins := map[string]*lubm.Manifest{}
+	for _, path := range paths {
+		m, err := lubm.ReadManifest(lubm.ManifestOf(path))
+		if err != nil {
+			origins = nil
+			break
+		}
+		origins[m.Digest] = m
+	}
+
+	for _, origin := range origins {
+		if len(origins) != 1 || len(origin.Files) != len(paths) {
+			break
+		}
+
+		manifest.Seed = origin.Seed
+		manifest.From = origin.From
+		manifest.To = origin.To
+		manifest.Partial = origin.Partial
+		manifest.MaxUniversityID = origin.MaxUniversityID
+		manifest.Profile = origin.Profile
+		manifest.Triples = origin.Triples
+		manifest.Digest = origin.Digest
+	}
+
+	for _, path := range paths {
+		format, _ := loader.FormatOf(path)
+		manifest.Files = append(manifest.Files, lubm.File{Path: path, Format: format.String()})
+	}
+
+	return manifest, paths
+}
+
+// prints progress of the generation
+func progress(p lubm.Progress, written string) {
+	if *quiet {
+		return
+	}
+
+	fmt.Printf("==> university %d (%d of %d), %d departments, %d triples (%.0f/s)%s, ETA %v\n",
+		p.University, p.Universities, p.Total, p.Departments, p.Triples, p.Rate(), written, p.ETA.Round(time.Second))
+}
+
+// exports entities of universities [from, to)
+func export(kind string, maxUniversityID, from, to int) {
+	if *outputFile == "" {
+		panic("export requires output directory -o")
+	}
+
+	var exporter interface {
+		lubm.Visitor
+		Close() error
+	}
+
+	var err error
+	switch kind {
+	case "graph":
+		exporter, err = graph.New(*outputFile, maxUniversityID)
+	case "sql":
+		exporter, err = relational.New(*outputFile, maxUniversityID)
+	case "datalog":
+		exporter, err = datalog.New(*outputFile)
+	case "dictionary":
+		exporter, err = dictionary.New(*outputFile, *seed, from, maxUniversityID)
+	default:
+		err = fmt.Errorf("unknown export %q, expected graph, sql, datalog or dictionary", kind)
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	t := time.Now()
+
+	// the university being exported is completed on interrupt
+	ctx, stop := interruptible()
+	completed := from
+
+	ds := lubm.NewDataSet(*seed, maxUniversityID, nil)
+	ds.OnProgress(to-from, func(p lubm.Progress) { progress(p, "") })
+	for i := from; i < to && ctx.Err() == nil; i++ {
+		if err := ds.Visit(i, exporter); err != nil {
+			panic(err)
+		}
+		completed = i + 1
+	}
+	stop()
+
+	if err := exporter.Close(); err != nil {
+		panic(err)
+	}
+
+	manifest := lubm.NewManifest(*seed, from, completed, maxUniversityID)
+	manifest.Partition = *partition
+	manifest.Partial = completed < to
+	manifest.Formats = []string{kind}
+	manifest.Elapsed = time.Since(t)
+	if err := manifest.WriteFile(lubm.ManifestOf(filepath.Clean(*outputFile))); err != nil {
+		panic(err)
+	}
+
+	if manifest.Partial {
+		fmt.Fprintf(os.Stderr, "==> interrupted, universities [%d, %d) are exported\n", from, completed)
+		os.Exit(130)
+	}
+
+	fmt.Printf("==> exported universities [%d, %d) to %s in %v\n", from, to, *outputFile, time.Since(t))
+}
+
+// context is done on SIGINT or SIGTERM. The signal handling is reset once
+// the context is done, the second signal terminates the process immediately.
+func interruptible() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// prints estimated size of the dataset
+func estimate(n int) {
+	e := lubm.EstimateOf(n)
+
+	if *jsonFile != "" {
+		if err := writeFile(*jsonFile, func(w io.Writer) error { return bench.WriteJSON(w, e) }); err != nil {
+			panic(err)
+		}
+	}
+
+	fmt.Printf("universities  %d\n", e.Universities)
+	fmt.Printf("departments   %d\n", e.Departments)
+	fmt.Printf("entities      %d\n", e.Entities)
+	fmt.Printf("triples       %d\n", e.Triples)
+
+	formats := make([]string, 0, len(e.Bytes))
+	for format := range e.Bytes {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+
+	for _, format := range formats {
+		fmt.Printf("%-13s %d bytes\n", format, e.Bytes[format])
+	}
+}
+
+// 10M, 500K, 1B or plain number
+func parseTriples(spec string) (int, error) {
+	num, scale := spec, 1.0
+	switch strings.ToUpper(spec[len(spec)-1:]) {
+	case "K":
+		scale = 1e3
+	case "M":
+		scale = 1e6
+	case "B", "G":
+		scale = 1e9
+	}
+	if scale != 1 {
+		num = spec[:len(spec)-1]
+	}
+
+	x, err := strconv.ParseFloat(num, 64)
+	if err != nil || x <= 0 {
+		return 0, fmt.Errorf("invalid number of triples %s, expected e.g. 10M", spec)
+	}
+
+	return int(x * scale), nil
+}
+
+// human readable size
+func bytesOf(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for x := n / unit; x >= unit; x /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// i/N
+func parsePartition(spec string, n int) (int, int, error) {
+	if spec == "" {
+		return 0, n, nil
+	}
+
+	a, b, ok := strings.Cut(spec, "/")
+	i, erri := strconv.Atoi(a)
+	p, errp := strconv.Atoi(b)
+	if !ok || erri != nil || errp != nil || p <= 0 || i < 0 || i >= p {
+		return 0, 0, fmt.Errorf("invalid partition %s, expected i/N where 0 ≤ i < N", spec)
+	}
+
+	from, to := lubm.Partition(n, i, p)
+	return from, to, nil
+}
+
+// university or triple budget
+func parseShard(spec string) (output.Sharding, int, error) {
+	switch spec {
+	case "":
+		return output.None, 0, nil
+	case "university":
+		return output.University, 0, nil
+	}
+
+	budget, err := strconv.Atoi(spec)
+	if err != nil || budget <= 0 {
+		return 0, 0, fmt.Errorf("invalid shard %s, expected university or number of triples", spec)
+	}
+
+	return output.Triples, budget, nil
+}
+
+// verifies loaded dataset against its manifest
+func verify(manifest *lubm.Manifest, size int, digest lubm.Digest) {
+	if manifest.Digest == "" {
+		return
+	}
+
+	if manifest.Triples != size || manifest.Digest != digest.String() {
+		fmt.Fprintf(os.Stderr, "loaded dataset does not match manifest: %d triples (digest %s), expected %d (digest %s)\n",
+			size, digest, manifest.Triples, manifest.Digest)
+		os.Exit(1)
+	}
+}
+
+// exposes SPARQL endpoint over the store
+func serve(store *ephemeral.Store) {
+	ep := endpoint.New(store, *timeout)
+	if *logQueries {
+		ep.Log = log.Default()
+	}
+	http.Handle("/sparql", ep)
+
+	fmt.Printf("==> serving SPARQL endpoint at http://%s/sparql\n", *addr)
+	if err := http.ListenAndServe(*addr, nil); err != nil {
+		panic(err)
+	}
+}
+
+// Query1=10,Query2=1
+func parseMix(spec string) (map[string]int, error) {
+	weights := map[string]int{}
+	if spec == "" {
+		return weights, nil
+	}
+
+	for _, kv := range strings.Split(spec, ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mix %s, expected Query=Weight", kv)
+		}
+
+		w, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight of %s: %w", k, err)
+		}
+		weights[strings.TrimSpace(k)] = w
+	}
+
+	return weights, nil
+}
+
+func writeFile(path string, f func(io.Writer) error) error {
+	fd, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	if err := f(fd); err != nil {
+		return err
+	}
 
-	seq := reader.ToSeq()
-	return len(seq), nil
+	return fd.Close()
 }
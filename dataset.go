@@ -11,83 +11,192 @@ package lubm
 import (
 	"encoding/json"
 	"math/rand"
+	"sort"
 	"strconv"
 
 	"github.com/kshard/spock"
 	"github.com/kshard/spock/encoding/jsonld"
+	"github.com/kshard/xsd"
 )
 
 type DataSet struct {
 	writer          chan<- spock.Bag
+	seed            int64
 	rand            *rand.Rand
 	maxUniversityID int
+	progress        tracker
 }
 
+// NewDataSet creates generator of universities [0, maxUniversityID).
+// Each university is seeded independently, the university is generated
+// identically regardless of order and of other universities generated by
+// the process.
 func NewDataSet(
 	seed int64,
 	maxUniversityID int,
 	writer chan<- spock.Bag,
 ) *DataSet {
-	rnd := rand.New(rand.NewSource(seed))
-	rnd.Seed(seed)
-	rand.Seed(seed)
-
 	return &DataSet{
-		rand:            rnd,
+		seed:            seed,
 		maxUniversityID: maxUniversityID,
 		writer:          writer,
 	}
 }
 
-func (ds DataSet) Write(obj any) error {
-	bin, err := json.Marshal(obj)
+// OnProgress sets callback of progress of the generation of n universities.
+// The callback is called periodically and once the university is generated,
+// it is called from the goroutine that generates the dataset.
+func (dataset *DataSet) OnProgress(n int, callback func(Progress)) {
+	dataset.progress = tracker{
+		Progress: Progress{Total: n},
+		callback: callback,
+	}
+}
+
+// Partition returns range of universities [from, to) assigned to the partition
+// i of n. Partitions are contiguous, concatenation of partitions in order is
+// the dataset of all universities.
+func Partition(universities, i, n int) (from, to int) {
+	return i * universities / n, (i + 1) * universities / n
+}
+
+// seedOf derives seed of the university using splitmix64 finalizer
+func seedOf(seed int64, universityID int) int64 {
+	z := uint64(seed) + uint64(universityID+1)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return int64(z ^ (z >> 31))
+}
+
+func (ds *DataSet) Write(obj any) error {
+	bag, err := Encode(obj)
 	if err != nil {
 		return err
 	}
 
+	ds.writer <- bag
+	return nil
+}
+
+// Encode entities into knowledge statements, same as they are written by
+// the dataset
+func Encode(obj any) (spock.Bag, error) {
+	bin, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
 	var bag jsonld.Bag
 	if err := json.Unmarshal(bin, &bag); err != nil {
-		return err
+		return nil, err
 	}
 
-	ds.writer <- spock.Bag(bag)
-	return nil
+	// JSON-LD decoder visits properties in random order, statements are ordered
+	// by entity then by predicate, multi-valued properties keep order of values.
+	entity := map[xsd.AnyURI]int{}
+	predicate := map[xsd.AnyURI]string{}
+	for _, x := range bag {
+		if _, has := entity[x.S]; !has {
+			entity[x.S] = len(entity)
+		}
+		if _, has := predicate[x.P]; !has {
+			predicate[x.P] = x.P.String()
+		}
+	}
+
+	sort.SliceStable(bag, func(i, j int) bool {
+		a, b := entity[bag[i].S], entity[bag[j].S]
+		if a != b {
+			return a < b
+		}
+		return predicate[bag[i].P] < predicate[bag[j].P]
+	})
+
+	return spock.Bag(bag), nil
+}
+
+// triples counts knowledge statements of the entity, same as encoded by
+// Encode but without encoding them
+func triples(entity any) int {
+	optional := func(seq ...*IRI) (n int) {
+		for _, x := range seq {
+			if x != nil {
+				n++
+			}
+		}
+		return
+	}
+
+	switch x := entity.(type) {
+	case *University:
+		return 2
+	case *Department:
+		return 3
+	case *Faculty:
+		return 6 + len(x.TeacherOf) + optional(x.HeadOf, x.UndergraduateDegreeFrom, x.MastersDegreeFrom, x.DoctoralDegreeFrom)
+	case *Student:
+		return 5 + len(x.TakesCourse) + optional(x.UndergraduateDegreeFrom, x.MastersDegreeFrom, x.Advisor, x.TeachingAssistantOf)
+	case *Course:
+		return 2
+	case *Publication:
+		return 2 + len(x.PublicationAuthor)
+	case *ResearchGroup:
+		return 2
+	default:
+		return 0
+	}
 }
 
 //
 // See http://swat.cse.lehigh.edu/projects/lubm/profile.htm
 //
 
+// Generate university and write its knowledge statements
 func (dataset *DataSet) Generate(universityID int) error {
-	return dataset.genUniversity(universityID, dataset.maxUniversityID)
+	return dataset.Visit(universityID, &rdf{dataset: dataset})
 }
 
-func (dataset *DataSet) genUniversity(universityID, maxUniversityID int) error {
-	university := newUniversity(universityID)
-	if err := dataset.Write(university); err != nil {
-		return err
-	}
+// entities of the university, the model is built before encoding
+type university struct {
+	*University
+	departments []*department
+}
+
+// entities of the department, the model is built before encoding
+type department struct {
+	*Department
+	faculties             []*Faculty
+	undergraduateStudents []*Student
+	graduateStudents      []*Student
+	courses               []*Course
+	graduateCourses       []*Course
+	publications          []*Publication
+	researchGroups        []*ResearchGroup
+
+	// graduated students, maintained by Updates only
+	alumni []*Student
+}
+
+func (dataset *DataSet) buildUniversity(universityID int) *university {
+	dataset.rand = rand.New(rand.NewSource(seedOf(dataset.seed, universityID)))
+	university := &university{University: newUniversity(universityID)}
 
 	// In each university
 	// 15~25 Departments are subOrgnization of the University
 	for id := 0; id < 15+dataset.rand.Intn(11); id++ {
-		dept := newDepartment(university, id)
-		if err := dataset.Write(dept); err != nil {
-			return err
-		}
-
-		dataset.genDepartment(dept)
+		dept := newDepartment(university.University, id)
+		university.departments = append(university.departments, dataset.buildDepartment(dept))
 	}
 
-	return nil
+	return university
 }
 
-func (dataset *DataSet) genDepartment(dept *Department) error {
+func (dataset *DataSet) buildDepartment(dept *Department) *department {
 	faculties := make([]*Faculty, 0)
 
 	// 7~10 FullProfessors worksFor the Department
 	for id := 0; id < 7+dataset.rand.Intn(4); id++ {
-		faculty := newProfessor(id, "Full", dept)
+		faculty := newProfessor(id, "Full", dept, dataset.rand)
 		faculties = append(faculties, faculty)
 	}
 	fullProfessors := len(faculties)
@@ -98,7 +207,7 @@ func (dataset *DataSet) genDepartment(dept *Department) error {
 
 	// 10~14 AssociateProfessors worksFor the Department
 	for id := 0; id < 10+dataset.rand.Intn(5); id++ {
-		faculty := newProfessor(id, "Associate", dept)
+		faculty := newProfessor(id, "Associate", dept, dataset.rand)
 
 		faculties = append(faculties, faculty)
 	}
@@ -106,7 +215,7 @@ func (dataset *DataSet) genDepartment(dept *Department) error {
 
 	// 8~11 AssistantProfessors worksFor the Department
 	for id := 0; id < 8+dataset.rand.Intn(4); id++ {
-		faculty := newProfessor(id, "Assistant", dept)
+		faculty := newProfessor(id, "Assistant", dept, dataset.rand)
 
 		faculties = append(faculties, faculty)
 	}
@@ -114,7 +223,7 @@ func (dataset *DataSet) genDepartment(dept *Department) error {
 
 	// 5~7 Lecturers worksFor the Department
 	for id := 0; id < 5+dataset.rand.Intn(3); id++ {
-		faculty := newLecturer(id, dept)
+		faculty := newLecturer(id, dept, dataset.rand)
 
 		faculties = append(faculties, faculty)
 	}
@@ -130,7 +239,7 @@ func (dataset *DataSet) genDepartment(dept *Department) error {
 	undergraduateStudents := make([]*Student, 0)
 	for range faculties {
 		for i := 0; i < 8+dataset.rand.Intn(7); i++ {
-			student := newUndergraduateStudent(len(undergraduateStudents), dept)
+			student := newUndergraduateStudent(len(undergraduateStudents), dept, dataset.rand)
 
 			undergraduateStudents = append(undergraduateStudents, student)
 		}
@@ -145,7 +254,7 @@ func (dataset *DataSet) genDepartment(dept *Department) error {
 	graduateStudents := make([]*Student, 0)
 	for range faculties {
 		for i := 0; i < 3+dataset.rand.Intn(2); i++ {
-			student := newGraduateStudent(len(graduateStudents), dept)
+			student := newGraduateStudent(len(graduateStudents), dept, dataset.rand)
 			// every GraudateStudent has an undergraduateDegreeFrom a University
 			student.UndergraduateDegreeFrom = dataset.degreeFromUniversity()
 			// every GraduateStudent has a Professor as his advisor
@@ -158,7 +267,7 @@ func (dataset *DataSet) genDepartment(dept *Department) error {
 	// every Faculty is teacherOf 1~2 Courses
 	courses := make([]*Course, 0)
 	for _, faculty := range faculties {
-		for i := 0; i < 1+rand.Intn(2); i++ {
+		for i := 0; i < 1+dataset.rand.Intn(2); i++ {
 			course := newCourse(len(courses), dept)
 			faculty.TeacherOf = append(faculty.TeacherOf, IRI(course.ID))
 
@@ -174,7 +283,7 @@ func (dataset *DataSet) genDepartment(dept *Department) error {
 	// every Faculty is teacherOf 1~2 GraduateCourses
 	graduateCourses := make([]*Course, 0)
 	for _, faculty := range faculties {
-		for i := 0; i < 1+rand.Intn(2); i++ {
+		for i := 0; i < 1+dataset.rand.Intn(2); i++ {
 			course := newGraduateCourse(len(graduateCourses), dept)
 			faculty.TeacherOf = append(faculty.TeacherOf, IRI(course.ID))
 
@@ -198,8 +307,8 @@ func (dataset *DataSet) genDepartment(dept *Department) error {
 
 	// every FullProfessor is publicationAuthor of 15~20 Publications
 	for _, professor := range faculties[:fullProfessors] {
-		for i := 0; i < 15+rand.Intn(6); i++ {
-			publication := newPublication(len(publications), dept, professor)
+		for i := 0; i < 15+dataset.rand.Intn(6); i++ {
+			publication := newPublication(len(publications), professor)
 
 			publications = append(publications, publication)
 		}
@@ -207,8 +316,8 @@ func (dataset *DataSet) genDepartment(dept *Department) error {
 
 	// every AssociateProfessor is publicationAuthor of 10~18 Publications
 	for _, professor := range faculties[fullProfessors:associateProfessors] {
-		for i := 0; i < 10+rand.Intn(9); i++ {
-			publication := newPublication(len(publications), dept, professor)
+		for i := 0; i < 10+dataset.rand.Intn(9); i++ {
+			publication := newPublication(len(publications), professor)
 
 			publications = append(publications, publication)
 		}
@@ -216,8 +325,8 @@ func (dataset *DataSet) genDepartment(dept *Department) error {
 
 	// every AssistantProfessor is publicationAuthor of 5~10 Publications
 	for _, professor := range faculties[associateProfessors:assistantProfessors] {
-		for i := 0; i < 5+rand.Intn(6); i++ {
-			publication := newPublication(len(publications), dept, professor)
+		for i := 0; i < 5+dataset.rand.Intn(6); i++ {
+			publication := newPublication(len(publications), professor)
 
 			publications = append(publications, publication)
 		}
@@ -225,8 +334,8 @@ func (dataset *DataSet) genDepartment(dept *Department) error {
 
 	// every Lecturer has 0~5 Publications
 	for _, professor := range faculties[assistantProfessors:] {
-		for i := 0; i < 0+rand.Intn(6); i++ {
-			publication := newPublication(len(publications), dept, professor)
+		for i := 0; i < 0+dataset.rand.Intn(6); i++ {
+			publication := newPublication(len(publications), professor)
 
 			publications = append(publications, publication)
 		}
@@ -241,35 +350,22 @@ func (dataset *DataSet) genDepartment(dept *Department) error {
 
 	// 10~20 ResearchGroups are subOrgnization of the Department
 	researchGroups := make([]*ResearchGroup, 0)
-	for i := 0; i < 10+rand.Intn(21); i++ {
+	for i := 0; i < 10+dataset.rand.Intn(21); i++ {
 		researchGroup := newResearchGroup(dept, i)
 
 		researchGroups = append(researchGroups, researchGroup)
 	}
 
-	if err := dataset.Write(faculties); err != nil {
-		return err
-	}
-	if err := dataset.Write(undergraduateStudents); err != nil {
-		return err
-	}
-	if err := dataset.Write(graduateStudents); err != nil {
-		return err
-	}
-	if err := dataset.Write(courses); err != nil {
-		return err
-	}
-	if err := dataset.Write(graduateCourses); err != nil {
-		return err
-	}
-	if err := dataset.Write(publications); err != nil {
-		return err
+	return &department{
+		Department:            dept,
+		faculties:             faculties,
+		undergraduateStudents: undergraduateStudents,
+		graduateStudents:      graduateStudents,
+		courses:               courses,
+		graduateCourses:       graduateCourses,
+		publications:          publications,
+		researchGroups:        researchGroups,
 	}
-	if err := dataset.Write(researchGroups); err != nil {
-		return err
-	}
-
-	return nil
 }
 
 func (dataset *DataSet) degreeFromUniversity() *IRI {
@@ -281,16 +377,17 @@ func (dataset *DataSet) degreeFromUniversity() *IRI {
 func (dataset *DataSet) takesCourse(min, max int, courses []*Course) []IRI {
 	set := map[IRI]struct{}{}
 
+	// sequence preserves order of the random choice
+	seq := make([]IRI, 0)
 	n := min + dataset.rand.Intn(max-min)
 	for i := 0; i < n; i++ {
 		course := courses[dataset.rand.Intn(len(courses))]
-		set[IRI(course.ID)] = struct{}{}
+		if _, has := set[IRI(course.ID)]; !has {
+			set[IRI(course.ID)] = struct{}{}
+			seq = append(seq, IRI(course.ID))
+		}
 	}
 
-	seq := make([]IRI, 0)
-	for iri := range set {
-		seq = append(seq, iri)
-	}
 	return seq
 }
 
@@ -309,30 +406,32 @@ func (dataset *DataSet) courseID(courses []*Course) *IRI {
 func (dataset *DataSet) fractionStudents(n int, students []*Student) []*Student {
 	set := map[UID]*Student{}
 
+	// sequence preserves order of the random choice
+	seq := make([]*Student, 0)
 	for i := 0; i < len(students)/5; i++ {
 		student := students[dataset.rand.Intn(len(students))]
-		set[student.ID] = student
+		if _, has := set[student.ID]; !has {
+			set[student.ID] = student
+			seq = append(seq, student)
+		}
 	}
 
-	seq := make([]*Student, 0)
-	for _, student := range set {
-		seq = append(seq, student)
-	}
 	return seq
 }
 
 func (dataset *DataSet) publications(n int, publications []*Publication) []*Publication {
 	set := map[UID]*Publication{}
 
+	// sequence preserves order of the random choice
+	seq := make([]*Publication, 0)
 	for i := 0; i < dataset.rand.Intn(n); i++ {
 		publication := publications[dataset.rand.Intn(len(publications))]
-		set[publication.ID] = publication
+		if _, has := set[publication.ID]; !has {
+			set[publication.ID] = publication
+			seq = append(seq, publication)
+		}
 	}
 
-	seq := make([]*Publication, 0)
-	for _, publication := range set {
-		seq = append(seq, publication)
-	}
 	return seq
 }
 
@@ -367,7 +466,7 @@ func newResearchGroup(dept *Department, id int) *ResearchGroup {
 	}
 }
 
-func newProfessor(id int, kind string, dept *Department) *Faculty {
+func newProfessor(id int, kind string, dept *Department, rnd *rand.Rand) *Faculty {
 	name := kind + "Professor" + strconv.Itoa(id)
 
 	return &Faculty{
@@ -377,12 +476,12 @@ func newProfessor(id int, kind string, dept *Department) *Faculty {
 		TeacherOf:        []IRI{},
 		WorksFor:         IRI(dept.ID),
 		EmailAddress:     string(dept.ID) + "@" + name,
-		Telephone:        telephone(),
+		Telephone:        telephone(rnd),
 		ResearchInterest: "Research0",
 	}
 }
 
-func newLecturer(id int, dept *Department) *Faculty {
+func newLecturer(id int, dept *Department, rnd *rand.Rand) *Faculty {
 	name := "Lecturer" + strconv.Itoa(id)
 
 	return &Faculty{
@@ -392,7 +491,7 @@ func newLecturer(id int, dept *Department) *Faculty {
 		TeacherOf:        []IRI{},
 		WorksFor:         IRI(dept.ID),
 		EmailAddress:     string(dept.ID) + "@" + name,
-		Telephone:        telephone(),
+		Telephone:        telephone(rnd),
 		ResearchInterest: "Research0",
 	}
 }
@@ -417,7 +516,7 @@ func newGraduateCourse(id int, dept *Department) *Course {
 	}
 }
 
-func newUndergraduateStudent(id int, dept *Department) *Student {
+func newUndergraduateStudent(id int, dept *Department, rnd *rand.Rand) *Student {
 	name := "UndergraduateStudent" + strconv.Itoa(id)
 
 	return &Student{
@@ -426,12 +525,12 @@ func newUndergraduateStudent(id int, dept *Department) *Student {
 		Name:         name,
 		MemberOf:     IRI(dept.ID),
 		EmailAddress: string(dept.ID) + "@" + name,
-		Telephone:    telephone(),
+		Telephone:    telephone(rnd),
 		TakesCourse:  []IRI{},
 	}
 }
 
-func newGraduateStudent(id int, dept *Department) *Student {
+func newGraduateStudent(id int, dept *Department, rnd *rand.Rand) *Student {
 	name := "GraduateStudent" + strconv.Itoa(id)
 
 	return &Student{
@@ -440,25 +539,25 @@ func newGraduateStudent(id int, dept *Department) *Student {
 		Name:         name,
 		MemberOf:     IRI(dept.ID),
 		EmailAddress: string(dept.ID) + "@" + name,
-		Telephone:    telephone(),
+		Telephone:    telephone(rnd),
 		TakesCourse:  []IRI{},
 	}
 }
 
-func newPublication(id int, dept *Department, faculty *Faculty) *Publication {
+func newPublication(id int, faculty *Faculty) *Publication {
 	name := "Publication" + strconv.Itoa(id)
 
 	return &Publication{
-		ID:                dept.ID + UID("/"+faculty.Name+"/"+name),
+		ID:                faculty.ID + UID("/"+name),
 		Type:              UID("ub:Publication"),
 		Name:              name,
 		PublicationAuthor: []IRI{IRI(faculty.ID)},
 	}
 }
 
-func telephone() string {
+func telephone(rnd *rand.Rand) string {
 	d3 := func() string {
-		return strconv.Itoa(rand.Intn(10)) + strconv.Itoa(rand.Intn(10)) + strconv.Itoa(rand.Intn(10))
+		return strconv.Itoa(rnd.Intn(10)) + strconv.Itoa(rnd.Intn(10)) + strconv.Itoa(rnd.Intn(10))
 	}
 
 	return d3() + "-" + d3() + "-" + d3()
@@ -0,0 +1,112 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package lubm_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kshard/lubm"
+	"github.com/kshard/lubm/encoding/ntriples"
+	"github.com/kshard/spock"
+)
+
+// writes universities [from, to) as N-Triples, as a standalone process does
+func ntriplesOf(t *testing.T, n, from, to int) []byte {
+	t.Helper()
+
+	bags, err := statements(n, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	enc := ntriples.NewEncoder(buf)
+	for _, bag := range bags.Bags {
+		if err := enc.Encode(bag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestPartition(t *testing.T) {
+	const n = 3
+	full := ntriplesOf(t, n, 0, n)
+
+	for _, parts := range []int{2, 3, 4} {
+		// partitions are generated in reverse order
+		seq := make([][]byte, parts)
+		for i := parts - 1; i >= 0; i-- {
+			from, to := lubm.Partition(n, i, parts)
+			seq[i] = ntriplesOf(t, n, from, to)
+		}
+
+		if !bytes.Equal(bytes.Join(seq, nil), full) {
+			t.Errorf("partitions of %d are not identical to the dataset", parts)
+		}
+	}
+}
+
+func TestPartitionRange(t *testing.T) {
+	for _, n := range []int{1, 7, 100, 1000} {
+		for _, parts := range []int{1, 3, 8, 16} {
+			next := 0
+			for i := 0; i < parts; i++ {
+				from, to := lubm.Partition(n, i, parts)
+				if from != next || to < from {
+					t.Fatalf("partition %d/%d of %d is [%d, %d)", i, parts, n, from, to)
+				}
+				next = to
+			}
+
+			if next != n {
+				t.Errorf("partitions of %d/%d cover %d universities", n, parts, next)
+			}
+		}
+	}
+}
+
+// progress accounts same triples whether the dataset is generated or visited
+func TestProgressTriples(t *testing.T) {
+	triples := 0
+	ch := make(chan spock.Bag)
+	done := make(chan struct{})
+	go func() {
+		for bag := range ch {
+			triples += len(bag)
+		}
+		close(done)
+	}()
+
+	var generated lubm.Progress
+	ds := lubm.NewDataSet(*seed, 1, ch)
+	ds.OnProgress(1, func(p lubm.Progress) { generated = p })
+	err := ds.Generate(0)
+	close(ch)
+	<-done
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var visited lubm.Progress
+	ds = lubm.NewDataSet(*seed, 1, nil)
+	ds.OnProgress(1, func(p lubm.Progress) { visited = p })
+	if err := ds.Visit(0, &lubm.Entities{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if generated.Triples != triples || visited.Triples != triples {
+		t.Errorf("progress of %d triples is %d generated and %d visited", triples, generated.Triples, visited.Triples)
+	}
+}
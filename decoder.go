@@ -0,0 +1,244 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package lubm
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/store/ephemeral"
+	"github.com/kshard/xsd"
+)
+
+// Decoder rebuilds entities from knowledge statements. Entities are decoded
+// in the order of the first statement about them, values of multi-valued
+// properties keep the order of statements. The generated dataset is decoded
+// exactly into entities passed to Visitor by DataSet.Visit, the store does
+// not preserve order, entities and values are ordered by the store.
+type Decoder struct {
+	order    []xsd.AnyURI
+	entities map[xsd.AnyURI][]spock.SPOCK
+}
+
+// NewDecoder creates decoder of entities
+func NewDecoder() *Decoder {
+	return &Decoder{entities: map[xsd.AnyURI][]spock.SPOCK{}}
+}
+
+// Add knowledge statements
+func (dec *Decoder) Add(bag spock.Bag) {
+	for _, x := range bag {
+		seq, has := dec.entities[x.S]
+		if !has {
+			dec.order = append(dec.order, x.S)
+		}
+		dec.entities[x.S] = append(seq, x)
+	}
+}
+
+// Read knowledge statements until io.EOF, e.g. from N-Triples decoder
+func (dec *Decoder) Read(r interface{ Read(spock.Bag) (int, error) }) error {
+	bag := make(spock.Bag, 1024)
+	for {
+		n, err := r.Read(bag)
+		dec.Add(bag[:n])
+
+		switch {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		}
+	}
+}
+
+// ReadStore reads all knowledge statements of the store
+func (dec *Decoder) ReadStore(store *ephemeral.Store) error {
+	stream, err := ephemeral.Match(store, spock.Pattern{Strategy: spock.STRATEGY_SPO})
+	if err != nil {
+		return err
+	}
+
+	bag := spock.Bag{}
+	for stream.Next() {
+		bag = append(bag, stream.Head())
+	}
+	dec.Add(bag)
+
+	return nil
+}
+
+// Decode entities and pass them to the visitor
+func (dec *Decoder) Decode(visitor Visitor) error {
+	for _, s := range dec.order {
+		seq := dec.entities[s]
+
+		var kind string
+		for _, x := range seq {
+			if iri, ok := x.O.(xsd.AnyURI); ok && x.P.String() == "rdf:type" {
+				kind = iri.String()
+				break
+			}
+		}
+
+		var err error
+		switch kind {
+		case "ub:University":
+			x := &University{}
+			if err = decodeEntity(x, seq); err == nil {
+				err = visitor.University(x)
+			}
+		case "ub:Department":
+			x := &Department{}
+			if err = decodeEntity(x, seq); err == nil {
+				err = visitor.Department(x)
+			}
+		case "ub:FullProfessor", "ub:AssociateProfessor", "ub:AssistantProfessor", "ub:Lecturer":
+			x := &Faculty{TeacherOf: []IRI{}}
+			if err = decodeEntity(x, seq); err == nil {
+				err = visitor.Faculty(x)
+			}
+		case "ub:UndergraduateStudent", "ub:GraduateStudent":
+			x := &Student{TakesCourse: []IRI{}}
+			if err = decodeEntity(x, seq); err == nil {
+				err = visitor.Student(x)
+			}
+		case "ub:Course", "ub:GraduateCourse":
+			x := &Course{}
+			if err = decodeEntity(x, seq); err == nil {
+				err = visitor.Course(x)
+			}
+		case "ub:Publication":
+			x := &Publication{PublicationAuthor: []IRI{}}
+			if err = decodeEntity(x, seq); err == nil {
+				err = visitor.Publication(x)
+			}
+		case "ub:ResearchGroup":
+			x := &ResearchGroup{}
+			if err = decodeEntity(x, seq); err == nil {
+				err = visitor.ResearchGroup(x)
+			}
+		default:
+			err = fmt.Errorf("%s: unknown type %q", s, kind)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decodes statements into the entity, properties are mapped by JSON tags
+// of the entity as they are encoded.
+func decodeEntity(entity any, seq []spock.SPOCK) error {
+	v := reflect.ValueOf(entity).Elem()
+	t := v.Type()
+
+	fields := map[string]reflect.Value{}
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		fields[tag] = v.Field(i)
+	}
+
+	for _, x := range seq {
+		fields["@id"].SetString(x.S.String())
+
+		p := x.P.String()
+		if p == "rdf:type" {
+			p = "@type"
+		}
+
+		field, has := fields[p]
+		if !has {
+			return fmt.Errorf("%s: unknown property %s of %s", x.S, p, t.Name())
+		}
+
+		iri, isIRI := x.O.(xsd.AnyURI)
+
+		switch field.Type() {
+		case reflect.TypeOf(UID("")), reflect.TypeOf(IRI("")):
+			if !isIRI {
+				return fmt.Errorf("%s: %s is not IRI", x.S, p)
+			}
+			field.SetString(iri.String())
+		case reflect.TypeOf((*IRI)(nil)):
+			if !isIRI {
+				return fmt.Errorf("%s: %s is not IRI", x.S, p)
+			}
+			ref := IRI(iri.String())
+			field.Set(reflect.ValueOf(&ref))
+		case reflect.TypeOf([]IRI{}):
+			if !isIRI {
+				return fmt.Errorf("%s: %s is not IRI", x.S, p)
+			}
+			field.Set(reflect.Append(field, reflect.ValueOf(IRI(iri.String()))))
+		case reflect.TypeOf(""):
+			s, ok := x.O.(xsd.String)
+			if !ok {
+				return fmt.Errorf("%s: %s is not string", x.S, p)
+			}
+			field.SetString(string(s))
+		default:
+			return fmt.Errorf("%s: %s of type %s is not supported", x.S, p, field.Type())
+		}
+	}
+
+	return nil
+}
+
+// Entities is visitor that collects entities
+type Entities struct {
+	Universities   []*University
+	Departments    []*Department
+	Faculties      []*Faculty
+	Students       []*Student
+	Courses        []*Course
+	Publications   []*Publication
+	ResearchGroups []*ResearchGroup
+}
+
+func (e *Entities) University(x *University) error {
+	e.Universities = append(e.Universities, x)
+	return nil
+}
+
+func (e *Entities) Department(x *Department) error {
+	e.Departments = append(e.Departments, x)
+	return nil
+}
+
+func (e *Entities) Faculty(x *Faculty) error {
+	e.Faculties = append(e.Faculties, x)
+	return nil
+}
+
+func (e *Entities) Student(x *Student) error {
+	e.Students = append(e.Students, x)
+	return nil
+}
+
+func (e *Entities) Course(x *Course) error {
+	e.Courses = append(e.Courses, x)
+	return nil
+}
+
+func (e *Entities) Publication(x *Publication) error {
+	e.Publications = append(e.Publications, x)
+	return nil
+}
+
+func (e *Entities) ResearchGroup(x *ResearchGroup) error {
+	e.ResearchGroups = append(e.ResearchGroups, x)
+	return nil
+}
@@ -0,0 +1,156 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package lubm_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/kshard/lubm"
+	"github.com/kshard/lubm/encoding/ntriples"
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/store/ephemeral"
+)
+
+// entities of the university and knowledge statements generated for it
+func entitiesOf(t *testing.T) (*lubm.Entities, []spock.Bag) {
+	t.Helper()
+
+	entities := &lubm.Entities{}
+	if err := lubm.NewDataSet(*seed, 2, nil).Visit(1, entities); err != nil {
+		t.Fatal(err)
+	}
+
+	bags, err := statements(2, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return entities, bags.Bags
+}
+
+func TestDecoder(t *testing.T) {
+	expect, bags := entitiesOf(t)
+
+	dec := lubm.NewDecoder()
+	for _, bag := range bags {
+		dec.Add(bag)
+	}
+
+	entities := &lubm.Entities{}
+	if err := dec.Decode(entities); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(entities, expect) {
+		t.Errorf("decoded entities are not equal to generated")
+	}
+}
+
+func TestDecoderNTriples(t *testing.T) {
+	expect, bags := entitiesOf(t)
+
+	buf := &bytes.Buffer{}
+	enc := ntriples.NewEncoder(buf)
+	for _, bag := range bags {
+		if err := enc.Encode(bag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := lubm.NewDecoder()
+	if err := dec.Read(ntriples.NewDecoder(buf)); err != nil {
+		t.Fatal(err)
+	}
+
+	entities := &lubm.Entities{}
+	if err := dec.Decode(entities); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(entities, expect) {
+		t.Errorf("decoded entities are not equal to generated")
+	}
+}
+
+// the store does not preserve order, entities are compared as sets
+func TestDecoderStore(t *testing.T) {
+	expect, bags := entitiesOf(t)
+
+	store := ephemeral.New()
+	for _, bag := range bags {
+		ephemeral.Add(store, bag)
+	}
+
+	dec := lubm.NewDecoder()
+	if err := dec.ReadStore(store); err != nil {
+		t.Fatal(err)
+	}
+
+	entities := &lubm.Entities{}
+	if err := dec.Decode(entities); err != nil {
+		t.Fatal(err)
+	}
+
+	if a, b := setOf(t, entities), setOf(t, expect); !reflect.DeepEqual(a, b) {
+		t.Errorf("decoded %d entities, expected %d", len(a), len(b))
+	}
+}
+
+// entities as canonical JSON with sorted multi-valued properties
+func setOf(t *testing.T, entities *lubm.Entities) []string {
+	t.Helper()
+
+	seq := []string{}
+	add := func(x any) {
+		v := reflect.ValueOf(x).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			if f, ok := v.Field(i).Interface().([]lubm.IRI); ok {
+				sort.Slice(f, func(i, j int) bool { return f[i] < f[j] })
+			}
+		}
+
+		bin, err := json.Marshal(x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seq = append(seq, string(bin))
+	}
+
+	for _, x := range entities.Universities {
+		add(x)
+	}
+	for _, x := range entities.Departments {
+		add(x)
+	}
+	for _, x := range entities.Faculties {
+		add(x)
+	}
+	for _, x := range entities.Students {
+		add(x)
+	}
+	for _, x := range entities.Courses {
+		add(x)
+	}
+	for _, x := range entities.Publications {
+		add(x)
+	}
+	for _, x := range entities.ResearchGroups {
+		add(x)
+	}
+
+	sort.Strings(seq)
+	return seq
+}
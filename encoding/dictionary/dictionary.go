@@ -0,0 +1,408 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+// Package dictionary implements dictionary encoding of knowledge statements
+// and the codec of dictionary-encoded dataset.
+//
+// Identifiers are dense, terms are numbered from 0 in the order they are
+// emitted by the dataset starting from University0, equal terms have the
+// same identifier. Identifiers are deterministic for the seed, dictionary
+// of the partition starting at university K replays terms of universities
+// [0, K) without writing them (see New). The replay generates preceding
+// universities, the last partition of the dataset costs nearly generation
+// of the whole dataset. The dictionary keeps in memory terms shared by
+// universities: vocabulary, universities and literals such as names and
+// telephones. Terms local to the university, its entities and e-mails, are
+// released once statements of the university are encoded.
+//
+// Universities are encoded in order and statements of the university are
+// contiguous, the university cannot refer to entities of other universities
+// except the universities themselves.
+//
+// The codec is text, one record per line, terms are defined before the first
+// triple referring to them:
+//
+//	id<TAB>term, term is IRI or literal in N-Triples syntax
+//	s<TAB>p<TAB>o
+//
+// Every file defines terms it refers to, files are decoded standalone.
+package dictionary
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/lubm"
+	"github.com/kshard/spock"
+	"github.com/kshard/xsd"
+)
+
+// Term of the dictionary, IRI is CURIE of the dataset
+type Term struct {
+	ID      uint64
+	Literal bool
+	Value   string
+}
+
+// String returns term in N-Triples syntax
+func (t Term) String() string {
+	if t.Literal {
+		return `"` + escape.Replace(t.Value) + `"`
+	}
+	return "<" + lubm.ToURI(curie.IRI(t.Value)) + ">"
+}
+
+// WriteTerm writes definition of the term, id<TAB>term
+func WriteTerm(w *bufio.Writer, t Term) error {
+	w.WriteString(strconv.FormatUint(t.ID, 10))
+	w.WriteByte('\t')
+	w.WriteString(t.String())
+	return w.WriteByte('\n')
+}
+
+var (
+	escape = strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"\t", `\t`,
+		"\n", `\n`,
+		"\r", `\r`,
+	)
+	unescape = strings.NewReplacer(
+		`\\`, `\`,
+		`\"`, `"`,
+		`\t`, "\t",
+		`\n`, "\n",
+		`\r`, "\r",
+	)
+)
+
+// university of the entity, e.g. edu:University7.Department1/Course3 ⟼ 7
+func universityOf(iri string) (int, bool) {
+	host, _, _ := strings.Cut(iri, "/")
+	host, _, _ = strings.Cut(host, ".")
+	return lubm.UniversityOf(lubm.IRI(host))
+}
+
+// university of the term local to it, e.g. edu:University7.Department1 or
+// edu:University7.Department1@Lecturer0 ⟼ 7. The university itself is not
+// local, it is referred by other universities.
+func localOf(value string) (int, bool) {
+	host, _, found := strings.Cut(value, ".")
+	if !found {
+		return 0, false
+	}
+	return lubm.UniversityOf(lubm.IRI(host))
+}
+
+// key of the term
+type key struct {
+	literal bool
+	value   string
+}
+
+// Dictionary assigns identifiers to terms of statements
+type Dictionary struct {
+	shared     map[key]uint64
+	local      map[key]uint64
+	next       uint64
+	university int // university of encoded statements
+	expect     int // university following encoded ones
+}
+
+// New creates dictionary of the dataset with universities
+// [0, maxUniversityID), statements are encoded starting from university
+// from. Terms of universities [0, from) are assigned by generating them.
+func New(seed int64, from, maxUniversityID int) (*Dictionary, error) {
+	d := &Dictionary{
+		shared:     map[key]uint64{},
+		local:      map[key]uint64{},
+		university: -1,
+	}
+
+	ds := lubm.NewDataSet(seed, maxUniversityID, nil)
+	for i := 0; i < from; i++ {
+		bags := &lubm.Statements{}
+		if err := ds.Visit(i, bags); err != nil {
+			return nil, err
+		}
+
+		for _, bag := range bags.Bags {
+			for _, x := range bag {
+				if _, err := d.Encode(x); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	d.expect = from
+	return d, nil
+}
+
+// Len is number of assigned identifiers, the next term is identified by it
+func (d *Dictionary) Len() int { return int(d.next) }
+
+// Encode returns terms of subject, predicate and object
+func (d *Dictionary) Encode(x spock.SPOCK) (terms [3]Term, err error) {
+	if terms[0], err = d.subject(x.S.String()); err != nil {
+		return
+	}
+
+	if terms[1], err = d.term(key{value: x.P.String()}); err != nil {
+		return
+	}
+
+	switch v := x.O.(type) {
+	case xsd.AnyURI:
+		terms[2], err = d.term(key{value: v.String()})
+	case xsd.String:
+		terms[2], err = d.term(key{literal: true, value: string(v)})
+	default:
+		err = fmt.Errorf("dictionary do not support %T (%v)", x.O, x.O)
+	}
+	return
+}
+
+// subject of the statement switches the university
+func (d *Dictionary) subject(iri string) (Term, error) {
+	k, ok := universityOf(iri)
+	if !ok {
+		return Term{}, fmt.Errorf("%s is not entity of university", iri)
+	}
+
+	if k != d.university {
+		if k != d.expect {
+			return Term{}, fmt.Errorf("statements of University%d are out of order, expected University%d", k, d.expect)
+		}
+
+		d.university, d.expect = k, k+1
+		d.local = map[key]uint64{}
+	}
+
+	return d.term(key{value: iri})
+}
+
+// identifier of the term
+func (d *Dictionary) term(t key) (Term, error) {
+	ids := d.shared
+	if k, ok := localOf(t.value); ok {
+		if k != d.university {
+			return Term{}, fmt.Errorf("%s is not term of University%d", t.value, d.university)
+		}
+		ids = d.local
+	}
+
+	id, has := ids[t]
+	if !has {
+		id = d.next
+		ids[t] = id
+		d.next++
+	}
+
+	return Term{ID: id, Literal: t.literal, Value: t.value}, nil
+}
+
+//
+// Codec
+//
+
+// Encoder writes dictionary-encoded statements
+type Encoder struct {
+	w       *bufio.Writer
+	dict    *Dictionary
+	first   uint64          // the first identifier assigned by the file
+	next    uint64          // the next identifier to be defined
+	defined map[uint64]bool // terms assigned before the file
+}
+
+// NewEncoder creates encoder of the file, the dictionary is shared by files
+// of the dataset. Flush has to be called after last bag.
+func NewEncoder(w io.Writer, dict *Dictionary) *Encoder {
+	return &Encoder{
+		w:       bufio.NewWriter(w),
+		dict:    dict,
+		first:   dict.next,
+		next:    dict.next,
+		defined: map[uint64]bool{},
+	}
+}
+
+// Encode writes bag of knowledge statements, one statement per line
+func (enc *Encoder) Encode(bag spock.Bag) error {
+	for _, x := range bag {
+		terms, err := enc.dict.Encode(x)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range terms {
+			switch {
+			case t.ID >= enc.next:
+				enc.next = t.ID + 1
+			case t.ID < enc.first && !enc.defined[t.ID]:
+				enc.defined[t.ID] = true
+			default:
+				continue
+			}
+
+			if err := WriteTerm(enc.w, t); err != nil {
+				return err
+			}
+		}
+
+		enc.w.WriteString(strconv.FormatUint(terms[0].ID, 10))
+		enc.w.WriteByte('\t')
+		enc.w.WriteString(strconv.FormatUint(terms[1].ID, 10))
+		enc.w.WriteByte('\t')
+		enc.w.WriteString(strconv.FormatUint(terms[2].ID, 10))
+		if err := enc.w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes buffered statements to the underlying writer
+func (enc *Encoder) Flush() error { return enc.w.Flush() }
+
+// Decoder reads dictionary-encoded statements
+type Decoder struct {
+	r          *bufio.Reader
+	line       int
+	university int
+	terms      map[uint64]Term
+	locals     map[int][]uint64 // terms local to the university
+}
+
+// NewDecoder creates decoder
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		r:          bufio.NewReaderSize(r, 64*1024),
+		university: -1,
+		terms:      map[uint64]Term{},
+		locals:     map[int][]uint64{},
+	}
+}
+
+// Decode reads next statement, it returns io.EOF at the end of input
+func (dec *Decoder) Decode() (spock.SPOCK, error) {
+	for {
+		line, err := dec.r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return spock.SPOCK{}, err
+		}
+		dec.line++
+
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			continue
+		}
+
+		seq := strings.Split(line, "\t")
+		switch len(seq) {
+		case 2:
+			err = dec.define(seq[0], seq[1])
+		case 3:
+			var x spock.SPOCK
+			if x, err = dec.triple(seq); err == nil {
+				return x, nil
+			}
+		default:
+			err = fmt.Errorf("unexpected %q", line)
+		}
+
+		if err != nil {
+			return spock.SPOCK{}, fmt.Errorf("line %d: %w", dec.line, err)
+		}
+	}
+}
+
+// Read reads up to len(bag) statements, it returns io.EOF at the end of input
+func (dec *Decoder) Read(bag spock.Bag) (int, error) {
+	for i := range bag {
+		x, err := dec.Decode()
+		if err != nil {
+			return i, err
+		}
+		bag[i] = x
+	}
+	return len(bag), nil
+}
+
+func (dec *Decoder) define(id, term string) error {
+	t := Term{}
+
+	var err error
+	if t.ID, err = strconv.ParseUint(id, 10, 64); err != nil {
+		return err
+	}
+
+	switch {
+	case len(term) >= 2 && term[0] == '<' && term[len(term)-1] == '>':
+		t.Value = string(lubm.FromURI(term[1 : len(term)-1]))
+	case len(term) >= 2 && term[0] == '"' && term[len(term)-1] == '"':
+		t.Literal, t.Value = true, unescape.Replace(term[1:len(term)-1])
+	default:
+		return fmt.Errorf("unexpected term %q", term)
+	}
+
+	dec.terms[t.ID] = t
+	if k, ok := localOf(t.Value); ok {
+		dec.locals[k] = append(dec.locals[k], t.ID)
+	}
+	return nil
+}
+
+func (dec *Decoder) triple(seq []string) (spock.SPOCK, error) {
+	var ids [3]uint64
+	for i, s := range seq {
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return spock.SPOCK{}, err
+		}
+		ids[i] = id
+	}
+
+	var terms [3]Term
+	for i, id := range ids {
+		t, has := dec.terms[id]
+		if !has {
+			return spock.SPOCK{}, fmt.Errorf("term %d is not defined", id)
+		}
+		terms[i] = t
+	}
+
+	// terms of the university are not referred after its statements
+	if k, ok := universityOf(terms[0].Value); ok && k != dec.university {
+		for _, id := range dec.locals[dec.university] {
+			delete(dec.terms, id)
+		}
+		delete(dec.locals, dec.university)
+		dec.university = k
+	}
+
+	for _, t := range terms[:2] {
+		if t.Literal {
+			return spock.SPOCK{}, fmt.Errorf("literal %q is used as IRI", t.Value)
+		}
+	}
+
+	x := spock.SPOCK{S: xsd.ToAnyURI(curie.IRI(terms[0].Value)), P: xsd.ToAnyURI(curie.IRI(terms[1].Value))}
+	if terms[2].Literal {
+		x.O = xsd.String(terms[2].Value)
+	} else {
+		x.O = xsd.ToAnyURI(curie.IRI(terms[2].Value))
+	}
+
+	return x, nil
+}
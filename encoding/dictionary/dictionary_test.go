@@ -0,0 +1,199 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package dictionary_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/kshard/lubm"
+	"github.com/kshard/lubm/encoding/dictionary"
+	"github.com/kshard/spock"
+)
+
+const seed = 1683234740
+
+// bags of universities [from, to) of the dataset
+func dataset(t *testing.T, n, from, to int) []spock.Bag {
+	t.Helper()
+
+	bags := &lubm.Statements{}
+	ds := lubm.NewDataSet(seed, n, nil)
+	for i := from; i < to; i++ {
+		if err := ds.Visit(i, bags); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return bags.Bags
+}
+
+func encode(t *testing.T, dict *dictionary.Dictionary, bags []spock.Bag) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	enc := dictionary.NewEncoder(buf, dict)
+	for _, bag := range bags {
+		if err := enc.Encode(bag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decode(t *testing.T, data []byte) spock.Bag {
+	t.Helper()
+
+	seq := spock.Bag{}
+	dec := dictionary.NewDecoder(bytes.NewReader(data))
+	for {
+		x, err := dec.Decode()
+		if err == io.EOF {
+			return seq
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		seq = append(seq, x)
+	}
+}
+
+func equal(t *testing.T, bags []spock.Bag, seq spock.Bag) {
+	t.Helper()
+
+	expect := spock.Bag{}
+	for _, bag := range bags {
+		expect = append(expect, bag...)
+	}
+
+	if len(seq) != len(expect) {
+		t.Fatalf("decoded %d triples, expected %d", len(seq), len(expect))
+	}
+	for i := range expect {
+		if seq[i] != expect[i] {
+			t.Fatalf("decoded %v, expected %v", seq[i], expect[i])
+		}
+	}
+}
+
+// dictionary of the dataset starting at university from
+func dictionaryOf(t *testing.T, n, from int) *dictionary.Dictionary {
+	t.Helper()
+
+	dict, err := dictionary.New(seed, from, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dict
+}
+
+// definitions and triples of the file
+func records(data []byte) (defs, triples []string) {
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		if strings.Count(line, "\t") == 1 {
+			defs = append(defs, line)
+		} else {
+			triples = append(triples, line)
+		}
+	}
+	return
+}
+
+func TestRoundTrip(t *testing.T) {
+	bags := dataset(t, 3, 0, 2)
+	equal(t, bags, decode(t, encode(t, dictionaryOf(t, 3, 0), bags)))
+}
+
+// terms are numbered from 0 in order of emission, each term once
+func TestDense(t *testing.T) {
+	const n = 3
+	dict := dictionaryOf(t, n, 0)
+	defs, _ := records(encode(t, dict, dataset(t, n, 0, n)))
+
+	if len(defs) != dict.Len() {
+		t.Fatalf("%d terms are defined, %d are assigned", len(defs), dict.Len())
+	}
+
+	terms := map[string]bool{}
+	for i, def := range defs {
+		id, term, _ := strings.Cut(def, "\t")
+		if id != strconv.Itoa(i) {
+			t.Fatalf("term %s is defined as %s", term, id)
+		}
+		if terms[term] {
+			t.Fatalf("term %s has multiple identifiers", term)
+		}
+		terms[term] = true
+	}
+}
+
+func TestPartition(t *testing.T) {
+	const n = 3
+	_, full := records(encode(t, dictionaryOf(t, n, 0), dataset(t, n, 0, n)))
+
+	// partitions are encoded in reverse order, each is decoded standalone
+	seq := make([][]string, n)
+	for i := n - 1; i >= 0; i-- {
+		bags := dataset(t, n, i, i+1)
+		data := encode(t, dictionaryOf(t, n, i), bags)
+		equal(t, bags, decode(t, data))
+		_, seq[i] = records(data)
+	}
+
+	if !reflect.DeepEqual(append(append(seq[0], seq[1]...), seq[2]...), full) {
+		t.Errorf("identifiers of partitions are not identical to the dataset")
+	}
+}
+
+// every file of the dataset is decoded standalone
+func TestShards(t *testing.T) {
+	bags := dataset(t, 2, 0, 2)
+
+	dict := dictionaryOf(t, 2, 0)
+	seq := spock.Bag{}
+	for _, bag := range bags {
+		seq = append(seq, decode(t, encode(t, dict, []spock.Bag{bag}))...)
+	}
+
+	equal(t, bags, seq)
+}
+
+func TestContiguous(t *testing.T) {
+	a := dataset(t, 2, 0, 1)
+	b := dataset(t, 2, 1, 2)
+
+	enc := dictionary.NewEncoder(io.Discard, dictionaryOf(t, 2, 0))
+	for _, bag := range [][]spock.Bag{a[:1], b, a[1:]} {
+		for _, x := range bag {
+			if err := enc.Encode(x); err != nil {
+				return
+			}
+		}
+	}
+
+	t.Errorf("statements of the university are not contiguous, no error")
+}
+
+// identifiers depend on universities encoded before
+func TestOrder(t *testing.T) {
+	enc := dictionary.NewEncoder(io.Discard, dictionaryOf(t, 3, 1))
+	for _, bag := range dataset(t, 3, 2, 3) {
+		if err := enc.Encode(bag); err != nil {
+			return
+		}
+	}
+
+	t.Errorf("university is skipped, no error")
+}
@@ -0,0 +1,355 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+// Package jsonld implements JSON-LD codec of knowledge statements. The
+// output is JSON Lines, each bag of statements is a standalone document
+// with @context and @graph of nodes. The dataset writes the bag per
+// department, so that every department is a document.
+//
+// Properties and classes are compacted with the dataset namespaces, nodes
+// are identified by fully qualified IRIs. References to nodes are written
+// as node objects, the context declares properties referring to nodes
+// (`"@type": "@id"`) and multi-valued properties (`"@container": "@set"`).
+//
+// Decoder reads documents written by the encoder or produced elsewhere.
+// Documents are node object, array of node objects or @graph. JSON-LD
+// processing is not implemented, terms are expanded with the dataset
+// namespaces. The context is optional, it may declare prefixes of the
+// dataset and definitions of terms as they are in Context, documents with
+// any other context are rejected.
+//
+// The context is derived from types of the dataset. A new property referring
+// to nodes extends it, documents written before are decoded but documents
+// written after are rejected by decoders unaware of the property. Changing
+// the property between single and multi-valued breaks documents both ways.
+//
+// See https://www.w3.org/TR/json-ld11/
+package jsonld
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/lubm"
+	"github.com/kshard/spock"
+	ld "github.com/kshard/spock/encoding/jsonld"
+	"github.com/kshard/xsd"
+)
+
+// ErrContext is returned when the context of the document declares terms
+// out of the context of the dataset
+var ErrContext = errors.New("jsonld context of the document is not compatible with the context of the dataset")
+
+// Context of the dataset, it is derived from entities of the dataset
+func Context() map[string]any {
+	context := map[string]any{}
+	for prefix, ns := range lubm.Namespaces {
+		context[prefix] = ns
+	}
+
+	for _, property := range properties {
+		def := map[string]string{"@type": "@id"}
+		if property.set {
+			def["@container"] = "@set"
+		}
+		context[property.name] = def
+	}
+
+	return context
+}
+
+type property struct {
+	name string
+	set  bool
+}
+
+// properties referring to nodes, as they are declared by entities
+var properties = func() []property {
+	seq := []property{}
+	seen := map[string]bool{}
+
+	for _, entity := range []any{
+		lubm.University{},
+		lubm.Department{},
+		lubm.Faculty{},
+		lubm.Student{},
+		lubm.Course{},
+		lubm.Publication{},
+		lubm.ResearchGroup{},
+	} {
+		t := reflect.TypeOf(entity)
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+
+			var set bool
+			switch t.Field(i).Type {
+			case reflect.TypeOf(lubm.IRI("")), reflect.TypeOf((*lubm.IRI)(nil)):
+			case reflect.TypeOf([]lubm.IRI{}):
+				set = true
+			default:
+				continue
+			}
+
+			if !seen[name] {
+				seen[name] = true
+				seq = append(seq, property{name: name, set: set})
+			}
+		}
+	}
+
+	return seq
+}()
+
+// sets of values, they are written as array regardless of number of values
+var sets = func() map[string]bool {
+	m := map[string]bool{}
+	for _, property := range properties {
+		m[property.name] = property.set
+	}
+	return m
+}()
+
+// Encoder writes knowledge statements as JSON-LD documents
+type Encoder struct {
+	w       *bufio.Writer
+	context json.RawMessage
+}
+
+// NewEncoder creates encoder, Flush has to be called after last bag
+func NewEncoder(w io.Writer) *Encoder {
+	// encoding/json sorts keys of maps, the context is stable
+	context, _ := json.Marshal(Context())
+
+	return &Encoder{
+		w:       bufio.NewWriter(w),
+		context: context,
+	}
+}
+
+// node of the graph, properties are kept in order of statements
+type node struct {
+	id         xsd.AnyURI
+	types      []string
+	predicates []string
+	values     map[string][]any
+}
+
+// Encode writes bag of knowledge statements as a document, one line per
+// document. Statements are grouped into nodes by subject.
+func (enc *Encoder) Encode(bag spock.Bag) error {
+	if len(bag) == 0 {
+		return nil
+	}
+
+	nodes := []*node{}
+	index := map[xsd.AnyURI]*node{}
+
+	for _, x := range bag {
+		n, has := index[x.S]
+		if !has {
+			n = &node{id: x.S, values: map[string][]any{}}
+			index[x.S] = n
+			nodes = append(nodes, n)
+		}
+
+		p := x.P.String()
+		if p == "rdf:type" {
+			if o, ok := x.O.(xsd.AnyURI); ok {
+				n.types = append(n.types, o.String())
+				continue
+			}
+		}
+
+		var v any
+		switch o := x.O.(type) {
+		case xsd.AnyURI:
+			v = map[string]string{"@id": lubm.ToURI(curie.IRI(o.String()))}
+		case xsd.String:
+			v = string(o)
+		default:
+			return fmt.Errorf("jsonld do not support %T (%v)", x.O, x.O)
+		}
+
+		if _, has := n.values[p]; !has {
+			n.predicates = append(n.predicates, p)
+		}
+		n.values[p] = append(n.values[p], v)
+	}
+
+	enc.w.WriteString(`{"@context":`)
+	enc.w.Write(enc.context)
+	enc.w.WriteString(`,"@graph":[`)
+	for i, n := range nodes {
+		if i > 0 {
+			enc.w.WriteByte(',')
+		}
+		if err := enc.encode(n); err != nil {
+			return err
+		}
+	}
+	_, err := enc.w.WriteString("]}\n")
+	return err
+}
+
+func (enc *Encoder) encode(n *node) error {
+	enc.w.WriteString(`{"@id":`)
+	if err := enc.value(lubm.ToURI(curie.IRI(n.id.String()))); err != nil {
+		return err
+	}
+
+	switch len(n.types) {
+	case 0:
+	case 1:
+		enc.w.WriteString(`,"@type":`)
+		enc.value(n.types[0])
+	default:
+		enc.w.WriteString(`,"@type":`)
+		enc.value(n.types)
+	}
+
+	for _, p := range n.predicates {
+		enc.w.WriteByte(',')
+		enc.value(p)
+		enc.w.WriteByte(':')
+
+		seq := n.values[p]
+		var err error
+		if len(seq) == 1 && !sets[p] {
+			err = enc.value(seq[0])
+		} else {
+			err = enc.value(seq)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return enc.w.WriteByte('}')
+}
+
+func (enc *Encoder) value(v any) error {
+	bin, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = enc.w.Write(bin)
+	return err
+}
+
+// Flush writes buffered documents to the underlying writer
+func (enc *Encoder) Flush() error { return enc.w.Flush() }
+
+//
+// Decoder
+//
+
+// Decoder reads knowledge statements from JSON-LD documents
+type Decoder struct {
+	r       *json.Decoder
+	context map[string]any
+}
+
+// NewDecoder creates decoder
+func NewDecoder(r io.Reader) *Decoder {
+	// terms are compared as decoded JSON values
+	var context map[string]any
+	bin, _ := json.Marshal(Context())
+	json.Unmarshal(bin, &context)
+
+	return &Decoder{r: json.NewDecoder(r), context: context}
+}
+
+// Decode reads next document, it returns io.EOF after the last one.
+func (dec *Decoder) Decode() (spock.Bag, error) {
+	var raw json.RawMessage
+	if err := dec.r.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	// node object or @graph is object, nodes are array
+	var doc any
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+		var nodes []map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &nodes); err != nil {
+			return nil, err
+		}
+		for _, node := range nodes {
+			if err := dec.strip(node); err != nil {
+				return nil, err
+			}
+		}
+		doc = nodes
+	} else {
+		var node map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &node); err != nil {
+			return nil, err
+		}
+		if err := dec.strip(node); err != nil {
+			return nil, err
+		}
+		doc = node
+	}
+
+	bin, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var bag ld.Bag
+	if err := json.Unmarshal(bin, &bag); err != nil {
+		return nil, err
+	}
+
+	seq := spock.Bag(bag)
+	for i := range seq {
+		seq[i] = compact(seq[i])
+	}
+
+	return seq, nil
+}
+
+// strip checks and removes @context of the node, it is not a node property
+// known to the graph codec
+func (dec *Decoder) strip(node map[string]json.RawMessage) error {
+	raw, has := node["@context"]
+	if !has {
+		return nil
+	}
+
+	// remote and array contexts are not supported
+	var context map[string]any
+	if err := json.Unmarshal(raw, &context); err != nil {
+		return ErrContext
+	}
+
+	for term, def := range context {
+		if !reflect.DeepEqual(def, dec.context[term]) {
+			return ErrContext
+		}
+	}
+
+	delete(node, "@context")
+	return nil
+}
+
+// compacts fully qualified IRIs into CURIE used by the dataset
+func compact(x spock.SPOCK) spock.SPOCK {
+	x.S = xsd.ToAnyURI(lubm.FromURI(x.S.String()))
+	x.P = xsd.ToAnyURI(lubm.FromURI(x.P.String()))
+	if o, ok := x.O.(xsd.AnyURI); ok {
+		x.O = xsd.ToAnyURI(lubm.FromURI(o.String()))
+	}
+	return x
+}
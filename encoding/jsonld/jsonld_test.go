@@ -0,0 +1,247 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package jsonld_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/kshard/lubm"
+	"github.com/kshard/lubm/encoding/jsonld"
+	"github.com/kshard/spock"
+)
+
+const (
+	ub  = "http://www.lehigh.edu/~zhp2/2004/0401/univ-bench.owl#"
+	rdf = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+)
+
+// expanded triples of entities
+var fixture = []string{
+	`<http://www.University0.edu> <` + rdf + `type> <` + ub + `University>`,
+	`<http://www.University0.edu> <` + ub + `name> "University0"`,
+	`<http://www.Department0.University0.edu> <` + rdf + `type> <` + ub + `Department>`,
+	`<http://www.Department0.University0.edu> <` + ub + `name> "Department0"`,
+	`<http://www.Department0.University0.edu> <` + ub + `subOrganizationOf> <http://www.University0.edu>`,
+	`<http://www.Department0.University0.edu/FullProfessor0> <` + rdf + `type> <` + ub + `FullProfessor>`,
+	`<http://www.Department0.University0.edu/FullProfessor0> <` + ub + `name> "FullProfessor0"`,
+	`<http://www.Department0.University0.edu/FullProfessor0> <` + ub + `headOf> <http://www.Department0.University0.edu>`,
+	`<http://www.Department0.University0.edu/FullProfessor0> <` + ub + `teacherOf> <http://www.Department0.University0.edu/Course0>`,
+	`<http://www.Department0.University0.edu/FullProfessor0> <` + ub + `teacherOf> <http://www.Department0.University0.edu/GraduateCourse0>`,
+	`<http://www.Department0.University0.edu/FullProfessor0> <` + ub + `doctoralDegreeFrom> <http://www.University5.edu>`,
+	`<http://www.Department0.University0.edu/FullProfessor0> <` + ub + `worksFor> <http://www.Department0.University0.edu>`,
+	`<http://www.Department0.University0.edu/FullProfessor0> <` + ub + `emailAddress> "FullProfessor0@Department0.University0.edu"`,
+	`<http://www.Department0.University0.edu/FullProfessor0> <` + ub + `telephone> "xxx-xxx-xxxx"`,
+	`<http://www.Department0.University0.edu/FullProfessor0> <` + ub + `researchInterest> "Research0"`,
+	`<http://www.Department0.University0.edu/FullProfessor0/Publication0> <` + rdf + `type> <` + ub + `Publication>`,
+	`<http://www.Department0.University0.edu/FullProfessor0/Publication0> <` + ub + `name> "Publication0"`,
+	`<http://www.Department0.University0.edu/FullProfessor0/Publication0> <` + ub + `publicationAuthor> <http://www.Department0.University0.edu/FullProfessor0>`,
+}
+
+// document of entities, the fixture
+func document(t *testing.T) (spock.Bag, []byte) {
+	t.Helper()
+
+	dept := lubm.IRI("edu:University0.Department0")
+	phd := lubm.IRI("edu:University5")
+
+	bag, err := lubm.Encode([]any{
+		&lubm.University{ID: "edu:University0", Type: "ub:University", Name: "University0"},
+		&lubm.Department{ID: "edu:University0.Department0", Type: "ub:Department", Name: "Department0", SubOrganizationOf: "edu:University0"},
+		&lubm.Faculty{
+			ID:                 "edu:University0.Department0/FullProfessor0",
+			Type:               "ub:FullProfessor",
+			Name:               "FullProfessor0",
+			HeadOf:             &dept,
+			TeacherOf:          []lubm.IRI{"edu:University0.Department0/Course0", "edu:University0.Department0/GraduateCourse0"},
+			DoctoralDegreeFrom: &phd,
+			WorksFor:           dept,
+			EmailAddress:       "FullProfessor0@Department0.University0.edu",
+			Telephone:          "xxx-xxx-xxxx",
+			ResearchInterest:   "Research0",
+		},
+		&lubm.Publication{
+			ID:                "edu:University0.Department0/FullProfessor0/Publication0",
+			Type:              "ub:Publication",
+			Name:              "Publication0",
+			PublicationAuthor: []lubm.IRI{"edu:University0.Department0/FullProfessor0"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	enc := jsonld.NewEncoder(buf)
+	if err := enc.Encode(bag); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	return bag, buf.Bytes()
+}
+
+// expand follows JSON-LD expansion of the document with @graph of nodes:
+// compact IRIs are expanded by prefixes of the context, strings are
+// references if the term is defined as "@type": "@id", literals otherwise.
+// Properties not expanded to absolute IRI are dropped by the expansion,
+// they are reported.
+func expand(t *testing.T, doc []byte) []string {
+	t.Helper()
+
+	var d struct {
+		Context map[string]any   `json:"@context"`
+		Graph   []map[string]any `json:"@graph"`
+	}
+	if err := json.Unmarshal(doc, &d); err != nil {
+		t.Fatal(err)
+	}
+
+	iri := func(s string) (string, bool) {
+		prefix, suffix, found := strings.Cut(s, ":")
+		switch {
+		case !found:
+			return "", false
+		case strings.HasPrefix(suffix, "//"):
+			return s, true
+		}
+		ns, ok := d.Context[prefix].(string)
+		return ns + suffix, ok
+	}
+
+	triples := []string{}
+	for _, node := range d.Graph {
+		id, ok := node["@id"].(string)
+		if !ok {
+			t.Fatalf("node %v has no @id", node)
+		}
+
+		for key, val := range node {
+			if key == "@id" {
+				continue
+			}
+
+			p, ok := iri(key)
+			coerce := false
+			if key == "@type" {
+				p, ok, coerce = rdf+"type", true, true
+			}
+			if def, has := d.Context[key].(map[string]any); has {
+				coerce = def["@type"] == "@id"
+			}
+			if !ok {
+				t.Errorf("%s is dropped by expansion", key)
+				continue
+			}
+
+			values, isSeq := val.([]any)
+			if !isSeq {
+				values = []any{val}
+			}
+
+			for _, v := range values {
+				var o string
+				switch x := v.(type) {
+				case string:
+					o = `"` + x + `"`
+					if coerce {
+						if o, ok = iri(x); !ok {
+							t.Errorf("%s of %s is not IRI", x, key)
+						}
+						o = "<" + o + ">"
+					}
+				case map[string]any:
+					ref, _ := x["@id"].(string)
+					if o, ok = iri(ref); !ok {
+						t.Errorf("%v of %s is not reference", x, key)
+					}
+					o = "<" + o + ">"
+				default:
+					t.Errorf("unexpected value %v of %s", v, key)
+				}
+
+				triples = append(triples, "<"+id+"> <"+p+"> "+o)
+			}
+		}
+	}
+
+	sort.Strings(triples)
+	return triples
+}
+
+func TestExpand(t *testing.T) {
+	_, doc := document(t)
+
+	expect := append([]string{}, fixture...)
+	sort.Strings(expect)
+
+	if triples := expand(t, doc); strings.Join(triples, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("expanded\n%s\nexpected\n%s", strings.Join(triples, "\n"), strings.Join(expect, "\n"))
+	}
+}
+
+// statements as sorted strings
+func setOf(bag spock.Bag) []string {
+	seq := make([]string, len(bag))
+	for i, x := range bag {
+		seq[i] = fmt.Sprintf("%v %v %v", x.S, x.P, x.O)
+	}
+	sort.Strings(seq)
+	return seq
+}
+
+func TestDecode(t *testing.T) {
+	bag, doc := document(t)
+
+	var d map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &d); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, doc := range map[string]string{
+		"document": string(doc),
+		"graph":    `{"@graph":` + string(d["@graph"]) + `}`,
+		"array":    string(d["@graph"]),
+		"prefixes": `{"@context":{"ub":"` + ub + `","rdf":"` + rdf + `"},"@graph":` + string(d["@graph"]) + `}`,
+	} {
+		seq, err := jsonld.NewDecoder(strings.NewReader(doc)).Decode()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		if a, b := setOf(seq), setOf(bag); strings.Join(a, "\n") != strings.Join(b, "\n") {
+			t.Errorf("%s: decoded %d statements, expected %d", name, len(a), len(b))
+		}
+	}
+}
+
+func TestDecodeContext(t *testing.T) {
+	const node = `"@id":"http://www.University0.edu","@type":"ub:University","ub:name":"University0"`
+
+	for name, doc := range map[string]string{
+		"prefix":     `{"@context":{"ub":"http://example.com/onto#"},` + node + `}`,
+		"vocab":      `{"@context":{"@vocab":"` + ub + `"},` + node + `}`,
+		"remote":     `{"@context":"http://example.com/context.jsonld",` + node + `}`,
+		"term":       `{"@context":{"ub:name":{"@type":"@id"}},` + node + `}`,
+		"node array": `[{"@context":{"ub":"http://example.com/onto#"},` + node + `}]`,
+	} {
+		_, err := jsonld.NewDecoder(strings.NewReader(doc)).Decode()
+		if !errors.Is(err, jsonld.ErrContext) {
+			t.Errorf("%s: context is accepted (%v)", name, err)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+// Package ntriples implements N-Triples codec of knowledge statements.
+// IRIs are expanded and compacted using the dataset namespaces, literals
+// are limited to xsd:string, the only data type supported by the store.
+//
+// See https://www.w3.org/TR/n-triples/
+package ntriples
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/lubm"
+	"github.com/kshard/spock"
+	"github.com/kshard/xsd"
+)
+
+const xsdString = "http://www.w3.org/2001/XMLSchema#string"
+
+// Encoder writes knowledge statements as N-Triples
+type Encoder struct {
+	w *bufio.Writer
+}
+
+// NewEncoder creates encoder, Flush has to be called after last bag
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w)}
+}
+
+// Encode writes bag of knowledge statements, one statement per line
+func (enc *Encoder) Encode(bag spock.Bag) error {
+	for _, x := range bag {
+		if err := enc.encode(x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (enc *Encoder) encode(x spock.SPOCK) error {
+	enc.w.WriteString(iriOf(x.S))
+	enc.w.WriteByte(' ')
+	enc.w.WriteString(iriOf(x.P))
+	enc.w.WriteByte(' ')
+
+	switch o := x.O.(type) {
+	case xsd.AnyURI:
+		enc.w.WriteString(iriOf(o))
+	case xsd.String:
+		enc.w.WriteByte('"')
+		enc.w.WriteString(escape.Replace(string(o)))
+		enc.w.WriteByte('"')
+	default:
+		return fmt.Errorf("ntriples do not support %T (%v)", x.O, x.O)
+	}
+
+	_, err := enc.w.WriteString(" .\n")
+	return err
+}
+
+// Flush writes buffered statements to the underlying writer
+func (enc *Encoder) Flush() error { return enc.w.Flush() }
+
+var escape = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+)
+
+func iriOf(iri xsd.AnyURI) string {
+	s := iri.String()
+	if strings.HasPrefix(s, "_:") {
+		return s
+	}
+	return "<" + lubm.ToURI(curie.IRI(s)) + ">"
+}
+
+// Decoder reads knowledge statements from N-Triples
+type Decoder struct {
+	r    *bufio.Reader
+	line int
+}
+
+// NewDecoder creates decoder
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// Decode reads next statement, it returns io.EOF at the end of input
+func (dec *Decoder) Decode() (spock.SPOCK, error) {
+	for {
+		line, err := dec.r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return spock.SPOCK{}, err
+		}
+		dec.line++
+
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		x, err := parse(line)
+		if err != nil {
+			return spock.SPOCK{}, fmt.Errorf("line %d: %w", dec.line, err)
+		}
+
+		return x, nil
+	}
+}
+
+// Read reads up to len(bag) statements, it returns io.EOF at the end of input
+func (dec *Decoder) Read(bag spock.Bag) (int, error) {
+	for i := range bag {
+		x, err := dec.Decode()
+		if err != nil {
+			return i, err
+		}
+		bag[i] = x
+	}
+	return len(bag), nil
+}
+
+//
+// parser of single statement
+//
+
+type lexer struct {
+	s   string
+	pos int
+}
+
+func parse(line string) (spock.SPOCK, error) {
+	lx := &lexer{s: line}
+
+	s, err := lx.subject()
+	if err != nil {
+		return spock.SPOCK{}, err
+	}
+
+	p, err := lx.iri()
+	if err != nil {
+		return spock.SPOCK{}, err
+	}
+
+	o, err := lx.object()
+	if err != nil {
+		return spock.SPOCK{}, err
+	}
+
+	lx.skip()
+	if !strings.HasPrefix(lx.s[lx.pos:], ".") {
+		return spock.SPOCK{}, errors.New("expected '.' at the end of statement")
+	}
+	lx.pos++
+
+	lx.skip()
+	if lx.pos < len(lx.s) && lx.s[lx.pos] != '#' {
+		return spock.SPOCK{}, fmt.Errorf("unexpected %q after statement", lx.s[lx.pos:])
+	}
+
+	return spock.SPOCK{S: s, P: p, O: o}, nil
+}
+
+func (lx *lexer) skip() {
+	for lx.pos < len(lx.s) && (lx.s[lx.pos] == ' ' || lx.s[lx.pos] == '\t') {
+		lx.pos++
+	}
+}
+
+func (lx *lexer) subject() (xsd.AnyURI, error) {
+	lx.skip()
+	if strings.HasPrefix(lx.s[lx.pos:], "_:") {
+		return lx.blank(), nil
+	}
+	return lx.iri()
+}
+
+func (lx *lexer) object() (xsd.Value, error) {
+	lx.skip()
+	switch {
+	case strings.HasPrefix(lx.s[lx.pos:], "_:"):
+		return lx.blank(), nil
+	case strings.HasPrefix(lx.s[lx.pos:], `"`):
+		return lx.literal()
+	default:
+		return lx.iri()
+	}
+}
+
+func (lx *lexer) blank() xsd.AnyURI {
+	start := lx.pos
+	lx.pos += 2
+	for lx.pos < len(lx.s) && !strings.ContainsRune(" \t<\".", rune(lx.s[lx.pos])) {
+		lx.pos++
+	}
+	return xsd.ToAnyURI(curie.IRI(lx.s[start:lx.pos]))
+}
+
+func (lx *lexer) iri() (xsd.AnyURI, error) {
+	lx.skip()
+	if lx.pos >= len(lx.s) || lx.s[lx.pos] != '<' {
+		return 0, fmt.Errorf("expected IRI at %q", lx.s[lx.pos:])
+	}
+
+	end := strings.IndexByte(lx.s[lx.pos:], '>')
+	if end == -1 {
+		return 0, errors.New("unterminated IRI")
+	}
+
+	uri, err := unescape(lx.s[lx.pos+1 : lx.pos+end])
+	if err != nil {
+		return 0, err
+	}
+	lx.pos += end + 1
+
+	return xsd.ToAnyURI(lubm.FromURI(uri)), nil
+}
+
+func (lx *lexer) literal() (xsd.Value, error) {
+	// opening quote is checked by caller
+	lx.pos++
+
+	end := lx.pos
+	for ; end < len(lx.s); end++ {
+		if lx.s[end] == '\\' {
+			end++
+			continue
+		}
+		if lx.s[end] == '"' {
+			break
+		}
+	}
+
+	if end >= len(lx.s) {
+		return nil, errors.New("unterminated literal")
+	}
+
+	val, err := unescape(lx.s[lx.pos:end])
+	if err != nil {
+		return nil, err
+	}
+	lx.pos = end + 1
+
+	switch {
+	case strings.HasPrefix(lx.s[lx.pos:], "^^"):
+		lx.pos += 2
+		dt := lx.s[lx.pos:]
+		n := strings.IndexByte(dt, '>')
+		if !strings.HasPrefix(dt, "<") || n == -1 {
+			return nil, errors.New("expected IRI of data type")
+		}
+		lx.pos += n + 1
+
+		if dt[1:n] != xsdString {
+			return nil, fmt.Errorf("data type %s is not supported", dt[1:n])
+		}
+	case strings.HasPrefix(lx.s[lx.pos:], "@"):
+		return nil, errors.New("language tags are not supported")
+	}
+
+	return xsd.String(val), nil
+}
+
+// unescape ECHAR and UCHAR sequences
+func unescape(s string) (string, error) {
+	if strings.IndexByte(s, '\\') == -1 {
+		return s, nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			sb.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		if i >= len(s) {
+			return "", errors.New("invalid escape sequence")
+		}
+
+		switch s[i] {
+		case 't':
+			sb.WriteByte('\t')
+		case 'b':
+			sb.WriteByte('\b')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'f':
+			sb.WriteByte('\f')
+		case '"', '\'', '\\':
+			sb.WriteByte(s[i])
+		case 'u', 'U':
+			n := 4
+			if s[i] == 'U' {
+				n = 8
+			}
+			if i+1+n > len(s) {
+				return "", errors.New("invalid unicode escape sequence")
+			}
+			code, err := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(code)) {
+				return "", errors.New("invalid unicode escape sequence")
+			}
+			sb.WriteRune(rune(code))
+			i += n
+		default:
+			return "", fmt.Errorf("invalid escape sequence \\%c", s[i])
+		}
+	}
+
+	return sb.String(), nil
+}
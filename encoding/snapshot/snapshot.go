@@ -0,0 +1,507 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+// Package snapshot implements compact binary format of the dataset. It is
+// about 8 times smaller than N-Triples and decoded about 7 times faster with
+// 20 times less allocations, see BenchmarkDecode.
+//
+// The snapshot does not make reload into ephemeral.Store much faster. The
+// store has no bulk load, every statement is inserted into six skiplist
+// indexes, which takes about 90% of the reload. Reloading the snapshot is
+// only 10-30% faster than N-Triples, see BenchmarkReload. Faster reload
+// requires a store that loads sorted blocks of statements.
+//
+// The snapshot is dictionary-encoded. It is written as a stream of blocks,
+// each block defines terms first seen in the block followed by triples of
+// term identities. Identities are assigned sequentially from 0.
+//
+//	snapshot := magic version header block* trailer
+//	magic    := "LUBMSNAP"
+//	version  := uvarint
+//	header   := uvarint(len) JSON(Header)
+//	block    := 'B' uvarint(len) uvarint(terms) term* uvarint(triples) (uvarint uvarint uvarint)* uint32(crc32c)
+//	term     := kind uvarint(len) bytes, kind is 'I' for IRI and 'S' for xsd:string
+//	trailer  := 'E' uvarint(terms) uvarint(triples) uint32(crc32c)
+//
+// Checksum of the block is CRC-32 (Castagnoli) of its len bytes, the block
+// is verified before any of its triples is decoded. Checksum of the trailer
+// is CRC-32 (Castagnoli) of all bytes preceding it.
+package snapshot
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/spock"
+	"github.com/kshard/xsd"
+)
+
+const (
+	magic   = "LUBMSNAP"
+	version = 2
+
+	tagBlock   = 'B'
+	tagTrailer = 'E'
+	kindIRI    = 'I'
+	kindString = 'S'
+)
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrChecksum is returned when snapshot is corrupted
+var ErrChecksum = errors.New("snapshot checksum mismatch")
+
+// Header of the snapshot, parameters of the generation
+type Header struct {
+	Seed            int64 `json:"seed"`
+	From            int   `json:"from"`
+	To              int   `json:"to"`
+	MaxUniversityID int   `json:"max_university_id"`
+}
+
+//
+// Encoder
+//
+
+// Encoder writes snapshot, Close has to be called after last bag
+type Encoder struct {
+	out    io.Writer
+	w      *bufio.Writer
+	hash   hash.Hash32
+	terms  map[xsd.Value]uint64
+	block  []byte
+	size   uint64
+	closed bool
+}
+
+// NewEncoder writes header of snapshot and creates encoder
+func NewEncoder(w io.Writer, header Header) (*Encoder, error) {
+	h := crc32.New(crc32c)
+	enc := &Encoder{
+		out:   w,
+		w:     bufio.NewWriterSize(io.MultiWriter(w, h), 64*1024),
+		hash:  h,
+		terms: map[xsd.Value]uint64{},
+	}
+
+	meta, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := []byte(magic)
+	buf = binary.AppendUvarint(buf, version)
+	buf = binary.AppendUvarint(buf, uint64(len(meta)))
+	buf = append(buf, meta...)
+	if _, err := enc.w.Write(buf); err != nil {
+		return nil, err
+	}
+
+	return enc, nil
+}
+
+// Encode writes bag of knowledge statements as a block
+func (enc *Encoder) Encode(bag spock.Bag) error {
+	if enc.closed {
+		return errors.New("snapshot is closed")
+	}
+
+	if len(bag) == 0 {
+		return nil
+	}
+
+	triples := make([]uint64, 0, 3*len(bag))
+	fresh := []xsd.Value{}
+	id := func(v xsd.Value) uint64 {
+		id, has := enc.terms[v]
+		if !has {
+			id = uint64(len(enc.terms))
+			enc.terms[v] = id
+			fresh = append(fresh, v)
+		}
+		return id
+	}
+
+	for _, x := range bag {
+		switch x.O.(type) {
+		case xsd.AnyURI, xsd.String:
+		default:
+			return fmt.Errorf("snapshot do not support %T (%v)", x.O, x.O)
+		}
+		triples = append(triples, id(x.S), id(x.P), id(x.O))
+	}
+
+	block := binary.AppendUvarint(enc.block[:0], uint64(len(fresh)))
+	for _, v := range fresh {
+		switch t := v.(type) {
+		case xsd.AnyURI:
+			block = append(block, kindIRI)
+			block = binary.AppendUvarint(block, uint64(len(t.String())))
+			block = append(block, t.String()...)
+		case xsd.String:
+			block = append(block, kindString)
+			block = binary.AppendUvarint(block, uint64(len(t)))
+			block = append(block, t...)
+		}
+	}
+
+	block = binary.AppendUvarint(block, uint64(len(bag)))
+	for _, id := range triples {
+		block = binary.AppendUvarint(block, id)
+	}
+	enc.block = block
+
+	head := binary.AppendUvarint([]byte{tagBlock}, uint64(len(block)))
+	if _, err := enc.w.Write(head); err != nil {
+		return err
+	}
+	if _, err := enc.w.Write(block); err != nil {
+		return err
+	}
+
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], crc32.Checksum(block, crc32c))
+	if _, err := enc.w.Write(sum[:]); err != nil {
+		return err
+	}
+
+	enc.size += uint64(len(bag))
+
+	return nil
+}
+
+// Close writes trailer and flushes the snapshot. It does not close
+// underlying writer.
+func (enc *Encoder) Close() error {
+	if enc.closed {
+		return nil
+	}
+	enc.closed = true
+
+	trailer := []byte{tagTrailer}
+	trailer = binary.AppendUvarint(trailer, uint64(len(enc.terms)))
+	trailer = binary.AppendUvarint(trailer, enc.size)
+	if _, err := enc.w.Write(trailer); err != nil {
+		return err
+	}
+	if err := enc.w.Flush(); err != nil {
+		return err
+	}
+
+	// checksum is not part of hashed content
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], enc.hash.Sum32())
+	_, err := enc.out.Write(sum[:])
+	return err
+}
+
+//
+// Decoder
+//
+
+// Decoder reads snapshot
+type Decoder struct {
+	r      *bufio.Reader
+	hash   hash.Hash32
+	header Header
+	terms  []xsd.Value
+	block  []byte
+	size   uint64
+	eof    bool
+}
+
+// NewDecoder reads header of snapshot and creates decoder
+func NewDecoder(r io.Reader) (*Decoder, error) {
+	dec := &Decoder{
+		r:    bufio.NewReaderSize(r, 64*1024),
+		hash: crc32.New(crc32c),
+	}
+
+	head := make([]byte, len(magic))
+	if err := dec.read(head); err != nil {
+		return nil, err
+	}
+	if string(head) != magic {
+		return nil, errors.New("not a snapshot")
+	}
+
+	v, err := dec.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if v != version {
+		return nil, fmt.Errorf("snapshot version %d is not supported", v)
+	}
+
+	n, err := dec.uvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := dec.readN(nil, n)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(meta, &dec.header); err != nil {
+		return nil, err
+	}
+
+	return dec, nil
+}
+
+// Header of the snapshot
+func (dec *Decoder) Header() Header { return dec.header }
+
+// read exactly len(p) bytes, bytes are added to the checksum
+func (dec *Decoder) read(p []byte) error {
+	n, err := io.ReadFull(dec.r, p)
+	dec.hash.Write(p[:n])
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// readN reads exactly n bytes into buf. The buffer grows while bytes are
+// read, corrupted length does not allocate more memory than the snapshot has.
+func (dec *Decoder) readN(buf []byte, n uint64) ([]byte, error) {
+	const chunk = 1 << 20
+
+	buf = buf[:0]
+	for uint64(len(buf)) < n {
+		size := n - uint64(len(buf))
+		if size > chunk {
+			size = chunk
+		}
+
+		at := len(buf)
+		if uint64(cap(buf)-at) < size {
+			buf = append(buf[:cap(buf)], make([]byte, at+int(size)-cap(buf))...)
+		}
+		buf = buf[:at+int(size)]
+
+		if err := dec.read(buf[at:]); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf, nil
+}
+
+// readByte reads single byte, it is added to the checksum
+func (dec *Decoder) readByte() (byte, error) {
+	b, err := dec.r.ReadByte()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	dec.hash.Write([]byte{b})
+	return b, nil
+}
+
+func (dec *Decoder) uvarint() (uint64, error) {
+	return binary.ReadUvarint(byteReader{dec})
+}
+
+type byteReader struct{ *Decoder }
+
+func (r byteReader) ReadByte() (byte, error) { return r.readByte() }
+
+// Decode reads next block, it returns io.EOF after the trailer once
+// the checksum is verified.
+func (dec *Decoder) Decode() (spock.Bag, error) {
+	if dec.eof {
+		return nil, io.EOF
+	}
+
+	tag, err := dec.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case tagBlock:
+		return dec.decodeBlock()
+	case tagTrailer:
+		if err := dec.decodeTrailer(); err != nil {
+			return nil, err
+		}
+		dec.eof = true
+		return nil, io.EOF
+	default:
+		return nil, fmt.Errorf("snapshot is corrupted, unknown tag %x", tag)
+	}
+}
+
+var errCorrupted = errors.New("snapshot is corrupted")
+
+func (dec *Decoder) decodeBlock() (spock.Bag, error) {
+	n, err := dec.uvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	dec.block, err = dec.readN(dec.block, n)
+	if err != nil {
+		return nil, err
+	}
+
+	var sum [4]byte
+	if err := dec.read(sum[:]); err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(sum[:]) != crc32.Checksum(dec.block, crc32c) {
+		return nil, ErrChecksum
+	}
+
+	r := &blockReader{buf: dec.block}
+
+	// terms of the block share single string, they are sliced from it
+	type term struct {
+		kind       byte
+		start, end int
+	}
+	seq := []term{}
+	for n := r.uvarint(); n > 0 && r.err == nil; n-- {
+		kind := r.byte()
+		size := r.uvarint()
+		if r.err != nil || size > uint64(len(r.buf)) {
+			r.err = errCorrupted
+			break
+		}
+		at := len(dec.block) - len(r.buf)
+		seq = append(seq, term{kind: kind, start: at, end: at + int(size)})
+		r.buf = r.buf[size:]
+	}
+
+	if r.err == nil && len(seq) > 0 {
+		start := seq[0].start
+		text := string(dec.block[start:seq[len(seq)-1].end])
+		for _, t := range seq {
+			switch t.kind {
+			case kindIRI:
+				dec.terms = append(dec.terms, xsd.ToAnyURI(curie.IRI(text[t.start-start:t.end-start])))
+			case kindString:
+				dec.terms = append(dec.terms, xsd.String(text[t.start-start:t.end-start]))
+			default:
+				r.err = errCorrupted
+			}
+		}
+	}
+
+	// every triple takes at least 3 bytes
+	n = r.uvarint()
+	if n > uint64(len(r.buf))/3 {
+		r.err = errCorrupted
+		n = 0
+	}
+
+	bag := make(spock.Bag, n)
+	for i := 0; i < len(bag) && r.err == nil; i++ {
+		bag[i].S = r.iri(dec.terms)
+		bag[i].P = r.iri(dec.terms)
+		bag[i].O = r.term(dec.terms)
+	}
+
+	if r.err == nil && len(r.buf) != 0 {
+		r.err = errCorrupted
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	dec.size += uint64(len(bag))
+
+	return bag, nil
+}
+
+// blockReader decodes block, the first error is sticky
+type blockReader struct {
+	buf []byte
+	err error
+}
+
+func (r *blockReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+
+	x, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = errCorrupted
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return x
+}
+
+func (r *blockReader) byte() byte {
+	if r.err != nil || len(r.buf) == 0 {
+		r.err = errCorrupted
+		return 0
+	}
+
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b
+}
+
+func (r *blockReader) term(terms []xsd.Value) xsd.Value {
+	id := r.uvarint()
+	if r.err != nil || id >= uint64(len(terms)) {
+		r.err = errCorrupted
+		return nil
+	}
+	return terms[id]
+}
+
+func (r *blockReader) iri(terms []xsd.Value) xsd.AnyURI {
+	iri, ok := r.term(terms).(xsd.AnyURI)
+	if !ok {
+		r.err = errCorrupted
+	}
+	return iri
+}
+
+func (dec *Decoder) decodeTrailer() error {
+	terms, err := dec.uvarint()
+	if err != nil {
+		return err
+	}
+
+	size, err := dec.uvarint()
+	if err != nil {
+		return err
+	}
+
+	expected := dec.hash.Sum32()
+
+	var sum [4]byte
+	if _, err := io.ReadFull(dec.r, sum[:]); err != nil {
+		return io.ErrUnexpectedEOF
+	}
+
+	if binary.LittleEndian.Uint32(sum[:]) != expected {
+		return ErrChecksum
+	}
+
+	if terms != uint64(len(dec.terms)) || size != dec.size {
+		return errCorrupted
+	}
+
+	return nil
+}
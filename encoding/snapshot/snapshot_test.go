@@ -0,0 +1,254 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package snapshot_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/kshard/lubm"
+	"github.com/kshard/lubm/encoding/ntriples"
+	"github.com/kshard/lubm/encoding/snapshot"
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/store/ephemeral"
+)
+
+var header = snapshot.Header{Seed: 1683234740, From: 0, To: 1, MaxUniversityID: 1}
+
+// bags of the single university dataset
+func dataset(tb testing.TB) []spock.Bag {
+	tb.Helper()
+
+	bags := &lubm.Statements{}
+	if err := lubm.NewDataSet(header.Seed, 1, nil).Visit(0, bags); err != nil {
+		tb.Fatal(err)
+	}
+	return bags.Bags
+}
+
+func encode(tb testing.TB, bags []spock.Bag) []byte {
+	tb.Helper()
+
+	buf := &bytes.Buffer{}
+	enc, err := snapshot.NewEncoder(buf, header)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	for _, bag := range bags {
+		if err := enc.Encode(bag); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// decodes snapshot, returns decoded blocks and the first error
+func decode(data []byte) ([]spock.Bag, error) {
+	dec, err := snapshot.NewDecoder(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	bags := []spock.Bag{}
+	for {
+		bag, err := dec.Decode()
+		switch {
+		case err == io.EOF:
+			return bags, nil
+		case err != nil:
+			return bags, err
+		}
+		bags = append(bags, bag)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	bags := dataset(t)
+	data := encode(t, bags)
+
+	dec, err := snapshot.NewDecoder(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dec.Header() != header {
+		t.Errorf("unexpected header %+v", dec.Header())
+	}
+
+	seq, err := decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(seq) != len(bags) {
+		t.Fatalf("decoded %d blocks, expected %d", len(seq), len(bags))
+	}
+	for i := range bags {
+		if len(seq[i]) != len(bags[i]) {
+			t.Fatalf("block %d has %d triples, expected %d", i, len(seq[i]), len(bags[i]))
+		}
+		for k := range bags[i] {
+			if seq[i][k] != bags[i][k] {
+				t.Fatalf("block %d: %v, expected %v", i, seq[i][k], bags[i][k])
+			}
+		}
+	}
+}
+
+func TestCorrupted(t *testing.T) {
+	bags := dataset(t)
+	data := encode(t, bags)
+
+	// bytes following the header
+	first := bytes.IndexByte(data, '}') + 1
+
+	for i := first; i < len(data); i += (len(data)-first)/251 + 1 {
+		corrupted := append([]byte{}, data...)
+		corrupted[i] ^= 0x20
+
+		seq, err := decode(corrupted)
+		if err == nil {
+			t.Fatalf("corruption at %d is not detected", i)
+		}
+
+		// blocks are verified before they are decoded
+		for k, bag := range seq {
+			for j := range bag {
+				if bag[j] != bags[k][j] {
+					t.Fatalf("corruption at %d yields %v", i, bag[j])
+				}
+			}
+		}
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	data := encode(t, dataset(t))
+
+	for _, n := range []int{len(data) - 1, len(data) - 4, len(data) / 2, 20} {
+		if _, err := decode(data[:n]); err == nil {
+			t.Errorf("truncated snapshot of %d bytes is accepted", n)
+		}
+	}
+}
+
+func TestLength(t *testing.T) {
+	data := encode(t, nil)
+
+	// header is followed by the block of enormous length
+	head := data[:len(data)-7]
+	block := append(append([]byte{}, head...), 'B', 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f)
+	if _, err := decode(block); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	data := encode(b, dataset(b))
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := decode(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// the dataset as N-Triples
+func ntriplesOf(b *testing.B) []byte {
+	b.Helper()
+
+	buf := &bytes.Buffer{}
+	enc := ntriples.NewEncoder(buf)
+	for _, bag := range dataset(b) {
+		if err := enc.Encode(bag); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		b.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func BenchmarkDecodeNTriples(b *testing.B) {
+	data := ntriplesOf(b)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	bag := make(spock.Bag, 1024)
+	for i := 0; i < b.N; i++ {
+		dec := ntriples.NewDecoder(bytes.NewReader(data))
+		for {
+			_, err := dec.Read(bag)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// reload is dominated by indexing of the store
+func BenchmarkReload(b *testing.B) {
+	data := encode(b, dataset(b))
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		store := ephemeral.New()
+		dec, err := snapshot.NewDecoder(bytes.NewReader(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+		for {
+			bag, err := dec.Decode()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+			ephemeral.Add(store, bag)
+		}
+	}
+}
+
+func BenchmarkReloadNTriples(b *testing.B) {
+	data := ntriplesOf(b)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	bag := make(spock.Bag, 1024)
+	for i := 0; i < b.N; i++ {
+		store := ephemeral.New()
+		dec := ntriples.NewDecoder(bytes.NewReader(data))
+		for {
+			n, err := dec.Read(bag)
+			ephemeral.Add(store, bag[:n])
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package lubm
+
+import "math"
+
+// Estimate of the dataset size, it is derived from expected values of
+// the profile ranges without generating the dataset.
+type Estimate struct {
+	Universities int              `json:"universities"`
+	Departments  int              `json:"departments"`
+	Entities     int              `json:"entities"`
+	Triples      int              `json:"triples"`
+	Bytes        map[string]int64 `json:"bytes"`
+}
+
+// Average size of the triple per output format, measured on the generated
+// dataset written with default compression level.
+var bytesPerTriple = map[string]float64{
+	"dictionary":    45.8,
+	"dictionary.gz": 6.7,
+	"jsonld":        65.7,
+	"jsonld.gz":     3.3,
+	"ntriples":      176.7,
+	"ntriples.gz":   5.3,
+	"snapshot":      22.2,
+	"snapshot.gz":   5.3,
+}
+
+// EstimateOf estimates size of the dataset of given number of universities
+func EstimateOf(universities int) Estimate {
+	dept := expectedDepartment()
+	depts := loop(15, 11)
+
+	u := float64(universities)
+	triples := u * (2 + depts*dept.triples)
+
+	estimate := Estimate{
+		Universities: universities,
+		Departments:  int(math.Round(u * depts)),
+		Entities:     int(math.Round(u * (1 + depts*dept.entities))),
+		Triples:      int(math.Round(triples)),
+		Bytes:        map[string]int64{},
+	}
+
+	for format, size := range bytesPerTriple {
+		estimate.Bytes[format] = int64(math.Round(triples * size))
+	}
+
+	return estimate
+}
+
+// UniversitiesFor returns number of universities of the dataset closest to
+// the given number of triples, the dataset has at least one university.
+func UniversitiesFor(triples int) int {
+	perUniversity := float64(EstimateOf(1000).Triples) / 1000
+	n := int(math.Round(float64(triples) / perUniversity))
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+type expected struct {
+	entities float64
+	triples  float64
+}
+
+// expected size of the department, follows buildDepartment
+func expectedDepartment() expected {
+	full := loop(7, 4)
+	associate := loop(10, 5)
+	assistant := loop(8, 4)
+	lecturers := loop(5, 3)
+	faculties := full + associate + assistant + lecturers
+
+	undergraduateStudents := faculties * loop(8, 7)
+	graduateStudents := faculties * loop(3, 2)
+	courses := faculties * loop(1, 2)
+	graduateCourses := faculties * loop(1, 2)
+
+	publications := full*loop(15, 6) +
+		associate*loop(10, 9) +
+		assistant*loop(5, 6) +
+		lecturers*loop(0, 6)
+
+	researchGroups := loop(10, 21)
+
+	// every draw of random subset yields distinct entities only
+	takesCourse := (distinct(courses, 2) + distinct(courses, 3)) / 2
+	takesGraduateCourse := (distinct(graduateCourses, 1) + distinct(graduateCourses, 2)) / 2
+	advisors := distinct(undergraduateStudents, math.Floor(undergraduateStudents/5))
+	teachingAssistants := distinct(graduateStudents, math.Floor(graduateStudents/5))
+	coauthors := distinct(publications, loop(0, 6))
+
+	triples := 3.0 + // department
+		faculties*9 + 1 + courses + graduateCourses + // faculties, head of department, teachers
+		undergraduateStudents*(5+takesCourse) + advisors +
+		graduateStudents*(7+takesGraduateCourse+coauthors) + teachingAssistants +
+		(courses+graduateCourses)*2 +
+		publications*3 +
+		researchGroups*2
+
+	entities := 1 + faculties + undergraduateStudents + graduateStudents +
+		courses + graduateCourses + publications + researchGroups
+
+	return expected{entities: entities, triples: triples}
+}
+
+// loop returns expected number of iterations of the loop
+//
+//	for i := 0; i < min+rand.Intn(n); i++ { ... }
+//
+// the bound is drawn again at every iteration, therefore the loop is
+// shorter than expected value of the range.
+func loop(min, n int) float64 {
+	expected, p := 0.0, 1.0
+	for i := 0; i < min+n; i++ {
+		if i >= min {
+			p *= float64(min+n-1-i) / float64(n)
+		}
+		expected += p
+	}
+	return expected
+}
+
+// distinct returns expected number of distinct items of k random choices
+// out of n items
+func distinct(n, k float64) float64 {
+	if n < 1 {
+		return 0
+	}
+	return n * (1 - math.Pow(1-1/n, k))
+}
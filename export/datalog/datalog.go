@@ -0,0 +1,175 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+// Package datalog exports the dataset as facts of Datalog relation
+// f(s, p, o), the relation queried by lubm.Query1 ... lubm.Query9. Values
+// are the same as they are in the knowledge statements, IRIs are CURIEs.
+//
+// The exporter writes into directory tab-separated facts and the queries
+// as a standalone program (https://souffle-lang.github.io):
+//
+//	f.facts
+//	lubm.dl
+//
+// The program reads facts from the directory and writes results of every
+// query into query1.csv ... query9.csv, e.g.
+//
+//	souffle -F dir -D . dir/lubm.dl
+package datalog
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/kshard/lubm"
+	"github.com/kshard/xsd"
+)
+
+// Writer of facts, it is lubm.Visitor
+type Writer struct {
+	fd *os.File
+	w  *bufio.Writer
+}
+
+// New creates writer of facts into the directory
+func New(dir string) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "lubm.dl"), []byte(Program()), 0644); err != nil {
+		return nil, err
+	}
+
+	fd, err := os.Create(filepath.Join(dir, "f.facts"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Writer{fd: fd, w: bufio.NewWriter(fd)}, nil
+}
+
+func (w *Writer) University(x *lubm.University) error       { return w.fact(x) }
+func (w *Writer) Department(x *lubm.Department) error       { return w.fact(x) }
+func (w *Writer) Faculty(x *lubm.Faculty) error             { return w.fact(x) }
+func (w *Writer) Student(x *lubm.Student) error             { return w.fact(x) }
+func (w *Writer) Course(x *lubm.Course) error               { return w.fact(x) }
+func (w *Writer) Publication(x *lubm.Publication) error     { return w.fact(x) }
+func (w *Writer) ResearchGroup(x *lubm.ResearchGroup) error { return w.fact(x) }
+
+// writes knowledge statements of entity as facts
+func (w *Writer) fact(entity any) error {
+	bag, err := lubm.Encode(entity)
+	if err != nil {
+		return err
+	}
+
+	for _, x := range bag {
+		var o string
+		switch v := x.O.(type) {
+		case xsd.AnyURI:
+			o = v.String()
+		case xsd.String:
+			o = string(v)
+		default:
+			return fmt.Errorf("datalog do not support %T (%v)", x.O, x.O)
+		}
+
+		// facts have no escaping
+		if strings.ContainsAny(o, "\t\n") {
+			return fmt.Errorf("datalog do not support value %q", o)
+		}
+
+		w.w.WriteString(x.S.String())
+		w.w.WriteByte('\t')
+		w.w.WriteString(x.P.String())
+		w.w.WriteByte('\t')
+		w.w.WriteString(o)
+		if err := w.w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close completes facts
+func (w *Writer) Close() error {
+	if err := w.w.Flush(); err != nil {
+		w.fd.Close()
+		return err
+	}
+	return w.fd.Close()
+}
+
+var (
+	// q(x, y) :-
+	head = regexp.MustCompile(`q\(([^)]*)\)\s*:-`)
+
+	// <edu:University0> or ub:name
+	constant = regexp.MustCompile(`<([^>]*)>|[A-Za-z]+:[A-Za-z]+`)
+)
+
+// Program returns lubm.Query1 ... lubm.Query9 with default parameters as
+// standalone program, query #id is relation queryID.
+func Program() string {
+	var sb strings.Builder
+
+	sb.WriteString(".decl f(s: symbol, p: symbol, o: symbol)\n")
+	sb.WriteString(".input f\n")
+
+	for i, q := range lubm.Queries() {
+		sb.WriteString("\n")
+		sb.WriteString(rule(i+1, q))
+	}
+
+	return sb.String()
+}
+
+// translates query into rule of relation queryID
+func rule(id int, query string) string {
+	name := fmt.Sprintf("query%d", id)
+
+	// query declares relation f(s, p, o) before the rule
+	_, body, _ := strings.Cut(query, "f(s, p, o).")
+
+	args := []string{}
+	if m := head.FindStringSubmatch(body); m != nil {
+		for _, arg := range strings.Split(m[1], ",") {
+			args = append(args, strings.TrimSpace(arg)+": symbol")
+		}
+	}
+
+	body = head.ReplaceAllString(body, name+"($1) :-")
+	body = constant.ReplaceAllStringFunc(body, func(s string) string {
+		return fmt.Sprintf("%q", strings.TrimSuffix(strings.TrimPrefix(s, "<"), ">"))
+	})
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "// Query%d\n", id)
+	fmt.Fprintf(&sb, ".decl %s(%s)\n", name, strings.Join(args, ", "))
+	fmt.Fprintf(&sb, ".output %s\n", name)
+
+	for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			sb.WriteString("\n")
+			continue
+		}
+		if !strings.HasSuffix(line, ":-") {
+			line = "  " + line
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	return sb.String()
+}
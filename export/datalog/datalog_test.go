@@ -0,0 +1,111 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package datalog_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/kshard/lubm"
+	"github.com/kshard/lubm/export/datalog"
+)
+
+const seed = 1683234740
+
+// every knowledge statement is a fact
+func TestWriter(t *testing.T) {
+	bags := &lubm.Statements{}
+	if err := lubm.NewDataSet(seed, 1, nil).Visit(0, bags); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	w, err := datalog.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lubm.NewDataSet(seed, 1, nil).Visit(0, w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	facts, err := os.ReadFile(filepath.Join(dir, "f.facts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(facts), "\n"), "\n")
+	if len(lines) != bags.Len() {
+		t.Errorf("%d facts, expected %d triples", len(lines), bags.Len())
+	}
+	for _, line := range lines {
+		if strings.Count(line, "\t") != 2 {
+			t.Fatalf("invalid fact %q", line)
+		}
+	}
+
+	program, err := os.ReadFile(filepath.Join(dir, "lubm.dl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(program) != datalog.Program() {
+		t.Errorf("unexpected program")
+	}
+}
+
+var (
+	head     = regexp.MustCompile(`q\(([^)]*)\)`)
+	decl     = regexp.MustCompile(`\.decl query\d+\(([^)]*)\)`)
+	constant = regexp.MustCompile(`<([^>]*)>|ub:[A-Za-z]+`)
+)
+
+// rules have same arity and atoms as queries, constants are symbols
+func TestProgram(t *testing.T) {
+	rules := strings.Split(datalog.Program(), "// Query")[1:]
+	queries := lubm.Queries()
+	if len(rules) != len(queries) {
+		t.Fatalf("program has %d rules, expected %d", len(rules), len(queries))
+	}
+
+	for i, expect := range queries {
+		rule := rules[i]
+		if !strings.HasPrefix(rule, fmt.Sprintf("%d\n", i+1)) {
+			t.Fatalf("unexpected rule Query%s", rule)
+		}
+
+		m := decl.FindStringSubmatch(rule)
+		if m == nil {
+			t.Fatalf("Query%d is not declared", i+1)
+		}
+		if a, b := len(strings.Split(m[1], ",")), len(strings.Split(head.FindStringSubmatch(expect)[1], ",")); a != b {
+			t.Errorf("Query%d has arity %d, expected %d", i+1, a, b)
+		}
+
+		// query declares f(s, p, o) before the rule
+		if a, b := strings.Count(rule, "f("), strings.Count(expect, "f(")-1; a != b {
+			t.Errorf("Query%d has %d atoms, expected %d", i+1, a, b)
+		}
+
+		for _, c := range constant.FindAllStringSubmatch(expect, -1) {
+			symbol := c[0]
+			if c[1] != "" {
+				symbol = c[1]
+			}
+			if !strings.Contains(rule, `"`+symbol+`"`) {
+				t.Errorf("Query%d does not refer %s", i+1, symbol)
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+// Package dictionary exports the dataset as dictionary-encoded triples.
+// Identifiers of terms are dense and same for every run of the same
+// dataset, see package encoding/dictionary.
+//
+// The exporter writes into directory the dictionary and triples:
+//
+//	dictionary.tsv
+//	  id<TAB>term, term is IRI or literal in N-Triples syntax
+//	triples.bin
+//	  subject, predicate and object as little-endian uint64
+//
+// The partition writes terms it assigns only, terms of preceding
+// universities are written by preceding partitions. Concatenation of
+// partitions is the dictionary and triples of the whole dataset.
+package dictionary
+
+import (
+	"bufio"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+
+	"github.com/kshard/lubm"
+	"github.com/kshard/lubm/encoding/dictionary"
+)
+
+// Writer of the dictionary and triples, it is lubm.Visitor
+type Writer struct {
+	dictionary *dictionary.Dictionary
+
+	dictfd  *os.File
+	dict    *bufio.Writer
+	triplfd *os.File
+	triples *bufio.Writer
+	buf     [24]byte
+	next    uint64 // the next identifier to be written
+}
+
+// New creates writer of the dictionary and triples of the dataset
+// [0, maxUniversityID) into the directory, universities are written
+// starting from university from.
+func New(dir string, seed int64, from, maxUniversityID int) (*Writer, error) {
+	dict, err := dictionary.New(seed, from, maxUniversityID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	dictfd, err := os.Create(filepath.Join(dir, "dictionary.tsv"))
+	if err != nil {
+		return nil, err
+	}
+
+	triplfd, err := os.Create(filepath.Join(dir, "triples.bin"))
+	if err != nil {
+		dictfd.Close()
+		return nil, err
+	}
+
+	return &Writer{
+		dictionary: dict,
+		next:       uint64(dict.Len()),
+		dictfd:     dictfd,
+		dict:       bufio.NewWriter(dictfd),
+		triplfd:    triplfd,
+		triples:    bufio.NewWriter(triplfd),
+	}, nil
+}
+
+func (w *Writer) University(x *lubm.University) error       { return w.write(x) }
+func (w *Writer) Department(x *lubm.Department) error       { return w.write(x) }
+func (w *Writer) Faculty(x *lubm.Faculty) error             { return w.write(x) }
+func (w *Writer) Student(x *lubm.Student) error             { return w.write(x) }
+func (w *Writer) Course(x *lubm.Course) error               { return w.write(x) }
+func (w *Writer) Publication(x *lubm.Publication) error     { return w.write(x) }
+func (w *Writer) ResearchGroup(x *lubm.ResearchGroup) error { return w.write(x) }
+
+// writes knowledge statements of entity as triples of identifiers
+func (w *Writer) write(entity any) error {
+	bag, err := lubm.Encode(entity)
+	if err != nil {
+		return err
+	}
+
+	for _, x := range bag {
+		terms, err := w.dictionary.Encode(x)
+		if err != nil {
+			return err
+		}
+
+		for i, t := range terms {
+			if t.ID >= w.next {
+				if err := dictionary.WriteTerm(w.dict, t); err != nil {
+					return err
+				}
+				w.next = t.ID + 1
+			}
+			binary.LittleEndian.PutUint64(w.buf[8*i:], t.ID)
+		}
+
+		if _, err := w.triples.Write(w.buf[:]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close completes the dictionary and triples
+func (w *Writer) Close() error {
+	var first error
+	keep := func(err error) {
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+
+	keep(w.dict.Flush())
+	keep(w.dictfd.Close())
+	keep(w.triples.Flush())
+	keep(w.triplfd.Close())
+
+	return first
+}
@@ -0,0 +1,120 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package dictionary_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kshard/lubm"
+	codec "github.com/kshard/lubm/encoding/dictionary"
+	"github.com/kshard/lubm/export/dictionary"
+)
+
+const seed = 1683234740
+
+// exports universities [from, to) of the dataset, returns dictionary and
+// triples
+func export(t *testing.T, n, from, to int) ([]byte, []byte) {
+	t.Helper()
+
+	dir := t.TempDir()
+	w, err := dictionary.New(dir, seed, from, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds := lubm.NewDataSet(seed, n, nil)
+	for i := from; i < to; i++ {
+		if err := ds.Visit(i, w); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dict, err := os.ReadFile(filepath.Join(dir, "dictionary.tsv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	triples, err := os.ReadFile(filepath.Join(dir, "triples.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dict, triples
+}
+
+// concatenation of partitions is the dataset, identifiers are same as
+// assigned by the codec
+func TestPartition(t *testing.T) {
+	const n = 2
+	dict, triples := export(t, n, 0, n)
+
+	da, ta := export(t, n, 0, 1)
+	db, tb := export(t, n, 1, 2)
+	if !bytes.Equal(append(da, db...), dict) || !bytes.Equal(append(ta, tb...), triples) {
+		t.Errorf("partitions are not identical to the dataset")
+	}
+
+	bags := &lubm.Statements{}
+	ds := lubm.NewDataSet(seed, n, nil)
+	for i := 0; i < n; i++ {
+		if err := ds.Visit(i, bags); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d, err := codec.New(seed, 0, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	enc := codec.NewEncoder(buf, d)
+	for _, bag := range bags.Bags {
+		if err := enc.Encode(bag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	seq := []string{}
+	for i := 0; i < len(triples); i += 24 {
+		seq = append(seq, fmt.Sprintf("%d\t%d\t%d",
+			binary.LittleEndian.Uint64(triples[i:]),
+			binary.LittleEndian.Uint64(triples[i+8:]),
+			binary.LittleEndian.Uint64(triples[i+16:]),
+		))
+	}
+
+	expect := []string{}
+	terms := []string{}
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if strings.Count(line, "\t") == 2 {
+			expect = append(expect, line)
+		} else {
+			terms = append(terms, line)
+		}
+	}
+
+	if strings.Join(seq, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("triples are not identical to the codec")
+	}
+	if string(dict) != strings.Join(terms, "\n")+"\n" {
+		t.Errorf("dictionary is not identical to the codec")
+	}
+}
@@ -0,0 +1,112 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+//
+// Queries match nodes by label and traverse typed edges, nodes are looked
+// up by the id property. Parameters are CURIEs, they are expanded to IRIs
+// used as node ids.
+//
+
+// Query1 is Cypher equivalent of lubm.Query1
+func Query1(course ...string) string {
+	return fmt.Sprintf(`MATCH (x:GraduateStudent)-[:takesCourse]->({id: %q})
+RETURN x.id`, param(course, "edu:University0.Department0/GraduateCourse5"))
+}
+
+// Query2 is Cypher equivalent of lubm.Query2
+func Query2() string {
+	return `MATCH (x:GraduateStudent)-[:undergraduateDegreeFrom]->(y:University),
+      (x)-[:memberOf]->(z:Department)-[:subOrganizationOf]->(y)
+RETURN y.id, z.id, x.id`
+}
+
+// Query3 is Cypher equivalent of lubm.Query3
+func Query3(author ...string) string {
+	return fmt.Sprintf(`MATCH (x:Publication)-[:publicationAuthor]->({id: %q})
+RETURN x.id`, param(author, "edu:University0.Department0/AssistantProfessor0"))
+}
+
+// Query4 is Cypher equivalent of lubm.Query4
+func Query4(dept ...string) string {
+	return fmt.Sprintf(`MATCH (x)-[:worksFor]->({id: %q})
+WHERE x.name IS NOT NULL AND x.emailAddress IS NOT NULL AND x.telephone IS NOT NULL
+RETURN x.id, x.name, x.emailAddress, x.telephone`, param(dept, "edu:University0.Department0"))
+}
+
+// Query5 is Cypher equivalent of lubm.Query5
+func Query5(dept ...string) string {
+	return fmt.Sprintf(`MATCH (x:UndergraduateStudent)-[:memberOf]->({id: %q})
+RETURN x.id`, param(dept, "edu:University0.Department0"))
+}
+
+// Query6 is Cypher equivalent of lubm.Query6
+func Query6() string {
+	return `MATCH (x:UndergraduateStudent)
+RETURN x.id`
+}
+
+// Query7 is Cypher equivalent of lubm.Query7
+func Query7(teacher ...string) string {
+	return fmt.Sprintf(`MATCH ({id: %q})-[:teacherOf]->(y:Course)<-[:takesCourse]-(x:UndergraduateStudent)
+RETURN x.id, y.id`, param(teacher, "edu:University0.Department0/AssistantProfessor0"))
+}
+
+// Query8 is Cypher equivalent of lubm.Query8
+func Query8(university ...string) string {
+	return fmt.Sprintf(`MATCH (x)-[:memberOf]->(y:Department)-[:subOrganizationOf]->({id: %q})
+WHERE x.emailAddress IS NOT NULL
+RETURN y.id, x.id, x.emailAddress`, param(university, "edu:University0"))
+}
+
+// Query9 is Cypher equivalent of lubm.Query9
+func Query9() string {
+	return `MATCH (x)-[:advisor]->(y)-[:teacherOf]->(z)<-[:takesCourse]-(x)
+RETURN DISTINCT x.id`
+}
+
+// queries with default parameters, query #id is at index id - 1
+func queries() []string {
+	return []string{
+		Query1(), Query2(), Query3(), Query4(), Query5(),
+		Query6(), Query7(), Query8(), Query9(),
+	}
+}
+
+// Query returns Cypher query #id (starting from 1) with default parameters
+func Query(id int) (string, error) {
+	seq := queries()
+	if id < 1 || id > len(seq) {
+		return "", fmt.Errorf("unknown query #%d", id)
+	}
+	return seq[id-1], nil
+}
+
+// Queries returns all queries as a script, queries are separated by
+// semicolon and preceded by comment with the name.
+func Queries() string {
+	var sb strings.Builder
+	for i, q := range queries() {
+		fmt.Fprintf(&sb, "// Query%d\n%s;\n\n", i+1, q)
+	}
+	return sb.String()
+}
+
+// parameter of the query or default value, CURIE is expanded to IRI
+func param(args []string, value string) string {
+	if len(args) != 0 {
+		value = args[0]
+	}
+	return uriOf(value)
+}
@@ -0,0 +1,407 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+// Package graph exports the dataset as property graph. Entities are nodes
+// labeled by their rdf:type, literal properties are properties of nodes and
+// properties referring to other entities are typed edges. Nodes are
+// identified by fully qualified IRIs.
+//
+// The exporter writes into directory the bulk-import layout common to
+// property-graph tools, node file per entity and single edge file, and
+// GraphML of the same graph:
+//
+//	University.csv, Department.csv, ..., ResearchGroup.csv
+//	  id:ID,name,...,:LABEL
+//	External.csv
+//	  id:ID
+//	edges.csv
+//	  :START_ID,:END_ID,:TYPE
+//	graph.graphml
+//	queries.cypher
+//
+// Degrees refer to any university of the dataset. Partitions of the dataset
+// are exported into own directories, every university node is written once
+// by its partition, so that edges of the partition may refer to nodes of
+// other partitions. Universities out of the dataset are written to
+// External.csv as nodes without label and properties, same as they are in RDF.
+package graph
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/lubm"
+)
+
+// Writer of the property graph, it is lubm.Visitor
+type Writer struct {
+	dir     string
+	nodes   map[reflect.Type]*table
+	edges   *table
+	graphml *os.File
+	gmlw    *bufio.Writer
+	gmledge *os.File
+	gmlebuf *bufio.Writer
+	edgeID  int
+
+	maxUniversityID int
+	referenced      map[lubm.IRI]bool // universities referenced by entities
+}
+
+// table is CSV file
+type table struct {
+	fd  *os.File
+	csv *csv.Writer
+}
+
+func newTable(path string, header []string) (*table, error) {
+	fd, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &table{fd: fd, csv: csv.NewWriter(fd)}
+	if err := t.csv.Write(header); err != nil {
+		fd.Close()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *table) Close() error {
+	t.csv.Flush()
+	if err := t.csv.Error(); err != nil {
+		t.fd.Close()
+		return err
+	}
+	return t.fd.Close()
+}
+
+// schema of the entity, derived from its JSON tags
+type schema struct {
+	name       string  // name of entity
+	properties []field // literal properties
+	references []field // properties referring to entities
+}
+
+type field struct {
+	index int
+	name  string
+}
+
+var schemas = map[reflect.Type]*schema{}
+
+// entities of the dataset in the order of node files
+var entities = []any{
+	lubm.University{},
+	lubm.Department{},
+	lubm.Faculty{},
+	lubm.Student{},
+	lubm.Course{},
+	lubm.Publication{},
+	lubm.ResearchGroup{},
+}
+
+func init() {
+	for _, entity := range entities {
+		t := reflect.TypeOf(entity)
+		s := &schema{name: t.Name()}
+
+		for i := 0; i < t.NumField(); i++ {
+			tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			name := localName(tag)
+
+			switch t.Field(i).Type {
+			case reflect.TypeOf(""):
+				s.properties = append(s.properties, field{index: i, name: name})
+			case reflect.TypeOf(lubm.IRI("")), reflect.TypeOf((*lubm.IRI)(nil)), reflect.TypeOf([]lubm.IRI{}):
+				s.references = append(s.references, field{index: i, name: name})
+			}
+		}
+
+		schemas[t] = s
+	}
+}
+
+// New creates writer of the property graph of the dataset with universities
+// [0, maxUniversityID) into the directory
+func New(dir string, maxUniversityID int) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	w := &Writer{
+		dir:             dir,
+		nodes:           map[reflect.Type]*table{},
+		maxUniversityID: maxUniversityID,
+		referenced:      map[lubm.IRI]bool{},
+	}
+
+	for _, entity := range entities {
+		t := reflect.TypeOf(entity)
+		s := schemas[t]
+
+		header := []string{"id:ID"}
+		for _, p := range s.properties {
+			header = append(header, p.name)
+		}
+		header = append(header, ":LABEL")
+
+		file, err := newTable(filepath.Join(dir, s.name+".csv"), header)
+		if err != nil {
+			w.close()
+			return nil, err
+		}
+		w.nodes[t] = file
+	}
+
+	var err error
+	w.edges, err = newTable(filepath.Join(dir, "edges.csv"), []string{":START_ID", ":END_ID", ":TYPE"})
+	if err != nil {
+		w.close()
+		return nil, err
+	}
+
+	// GraphML requires keys before the graph, edges are appended after nodes
+	w.graphml, err = os.Create(filepath.Join(dir, "graph.graphml"))
+	if err != nil {
+		w.close()
+		return nil, err
+	}
+	w.gmlw = bufio.NewWriter(w.graphml)
+
+	w.gmledge, err = os.CreateTemp(dir, "graph.graphml.edges.*")
+	if err != nil {
+		w.close()
+		return nil, err
+	}
+	w.gmlebuf = bufio.NewWriter(w.gmledge)
+
+	w.writeGraphMLHeader()
+
+	if err := os.WriteFile(filepath.Join(dir, "queries.cypher"), []byte(Queries()), 0644); err != nil {
+		w.close()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *Writer) writeGraphMLHeader() {
+	w.gmlw.WriteString(xml.Header)
+	w.gmlw.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	w.gmlw.WriteString(`  <key id="labels" for="node" attr.name="labels" attr.type="string"/>` + "\n")
+	w.gmlw.WriteString(`  <key id="label" for="edge" attr.name="label" attr.type="string"/>` + "\n")
+
+	keys := map[string]bool{}
+	for _, entity := range entities {
+		for _, p := range schemas[reflect.TypeOf(entity)].properties {
+			keys[p.name] = true
+		}
+	}
+
+	seq := make([]string, 0, len(keys))
+	for key := range keys {
+		seq = append(seq, key)
+	}
+	sort.Strings(seq)
+
+	for _, key := range seq {
+		fmt.Fprintf(w.gmlw, `  <key id="%s" for="node" attr.name="%s" attr.type="string"/>`+"\n", key, key)
+	}
+
+	w.gmlw.WriteString(`  <graph id="lubm" edgedefault="directed">` + "\n")
+}
+
+func (w *Writer) University(x *lubm.University) error       { return w.node(x) }
+func (w *Writer) Department(x *lubm.Department) error       { return w.node(x) }
+func (w *Writer) Faculty(x *lubm.Faculty) error             { return w.node(x) }
+func (w *Writer) Student(x *lubm.Student) error             { return w.node(x) }
+func (w *Writer) Course(x *lubm.Course) error               { return w.node(x) }
+func (w *Writer) Publication(x *lubm.Publication) error     { return w.node(x) }
+func (w *Writer) ResearchGroup(x *lubm.ResearchGroup) error { return w.node(x) }
+
+// writes entity as node and its references as edges
+func (w *Writer) node(entity any) error {
+	v := reflect.ValueOf(entity).Elem()
+	s := schemas[v.Type()]
+
+	id := uriOf(v.FieldByName("ID").String())
+	label := localName(v.FieldByName("Type").String())
+
+	row := []string{id}
+	for _, p := range s.properties {
+		row = append(row, v.Field(p.index).String())
+	}
+	row = append(row, label)
+
+	if err := w.nodes[v.Type()].csv.Write(row); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w.gmlw, `    <node id="%s"><data key="labels">:%s</data>`, escape(id), escape(label))
+	for _, p := range s.properties {
+		fmt.Fprintf(w.gmlw, `<data key="%s">%s</data>`, p.name, escape(v.Field(p.index).String()))
+	}
+	w.gmlw.WriteString("</node>\n")
+
+	for _, ref := range s.references {
+		for _, target := range references(v.Field(ref.index)) {
+			if err := w.edge(id, target, ref.name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// IRIs of the reference field
+func references(v reflect.Value) []lubm.IRI {
+	switch x := v.Interface().(type) {
+	case lubm.IRI:
+		return []lubm.IRI{x}
+	case *lubm.IRI:
+		if x == nil {
+			return nil
+		}
+		return []lubm.IRI{*x}
+	case []lubm.IRI:
+		return x
+	default:
+		return nil
+	}
+}
+
+func (w *Writer) edge(source string, target lubm.IRI, kind string) error {
+	if isUniversity(target) {
+		w.referenced[target] = true
+	}
+
+	dest := uriOf(string(target))
+	if err := w.edges.csv.Write([]string{source, dest, kind}); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w.gmlebuf, `    <edge id="e%d" source="%s" target="%s"><data key="label">%s</data></edge>`+"\n",
+		w.edgeID, escape(source), escape(dest), kind)
+	w.edgeID++
+	return err
+}
+
+// Close writes nodes of universities referenced out of the dataset and
+// completes files. Universities of the dataset are nodes of partitions.
+func (w *Writer) Close() error {
+	external, err := newTable(filepath.Join(w.dir, "External.csv"), []string{"id:ID"})
+	if err != nil {
+		w.close()
+		return err
+	}
+
+	seq := make([]string, 0)
+	for iri := range w.referenced {
+		if id, ok := lubm.UniversityOf(iri); !ok || id >= w.maxUniversityID {
+			seq = append(seq, uriOf(string(iri)))
+		}
+	}
+	sort.Strings(seq)
+
+	for _, id := range seq {
+		if err := external.csv.Write([]string{id}); err != nil {
+			external.Close()
+			w.close()
+			return err
+		}
+		fmt.Fprintf(w.gmlw, `    <node id="%s"/>`+"\n", escape(id))
+	}
+
+	if err := external.Close(); err != nil {
+		w.close()
+		return err
+	}
+
+	// appends edges to GraphML
+	if err := w.gmlebuf.Flush(); err != nil {
+		w.close()
+		return err
+	}
+	if _, err := w.gmledge.Seek(0, io.SeekStart); err != nil {
+		w.close()
+		return err
+	}
+	if _, err := io.Copy(w.gmlw, w.gmledge); err != nil {
+		w.close()
+		return err
+	}
+	w.gmlw.WriteString("  </graph>\n</graphml>\n")
+	if err := w.gmlw.Flush(); err != nil {
+		w.close()
+		return err
+	}
+
+	return w.close()
+}
+
+// closes all files, returns the first error
+func (w *Writer) close() error {
+	var first error
+	keep := func(err error) {
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+
+	for _, t := range w.nodes {
+		keep(t.Close())
+	}
+	if w.edges != nil {
+		keep(w.edges.Close())
+	}
+	if w.graphml != nil {
+		keep(w.graphml.Close())
+	}
+	if w.gmledge != nil {
+		keep(w.gmledge.Close())
+		keep(os.Remove(w.gmledge.Name()))
+	}
+
+	w.nodes, w.edges, w.graphml, w.gmledge = nil, nil, nil, nil
+	return first
+}
+
+// edu:University0 is reference to university
+func isUniversity(iri lubm.IRI) bool {
+	prefix, ref := curie.Seq(curie.IRI(iri))
+	return prefix == "edu" && !strings.ContainsAny(ref, "./")
+}
+
+func uriOf(iri string) string { return lubm.ToURI(curie.IRI(iri)) }
+
+// ub:name ⟼ name
+func localName(iri string) string {
+	if _, name, ok := strings.Cut(iri, ":"); ok {
+		return name
+	}
+	return iri
+}
+
+func escape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
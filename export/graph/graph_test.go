@@ -0,0 +1,128 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package graph_test
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/lubm"
+	"github.com/kshard/lubm/export/graph"
+)
+
+const seed = 1683234740
+
+func rowsOf(t *testing.T, dir, file string) [][]string {
+	t.Helper()
+
+	fd, err := os.Open(filepath.Join(dir, file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	rows, err := csv.NewReader(fd).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows[1:]
+}
+
+// every knowledge statement is either label or property of node or edge
+func TestWriter(t *testing.T) {
+	bags := &lubm.Statements{}
+	if err := lubm.NewDataSet(seed, 1, nil).Visit(0, bags); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	w, err := graph.New(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lubm.NewDataSet(seed, 1, nil).Visit(0, w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	nodes, triples := 0, 0
+	for _, file := range []string{"University", "Department", "Faculty", "Student", "Course", "Publication", "ResearchGroup"} {
+		for _, row := range rowsOf(t, dir, file+".csv") {
+			nodes++
+			// id is subject, other columns are properties and label
+			for _, value := range row[1:] {
+				if value != "" {
+					triples++
+				}
+			}
+		}
+	}
+
+	edges := len(rowsOf(t, dir, "edges.csv"))
+	if triples+edges != bags.Len() {
+		t.Errorf("graph has %d properties and %d edges, expected %d triples", triples, edges, bags.Len())
+	}
+
+	external := len(rowsOf(t, dir, "External.csv"))
+	gml, err := os.ReadFile(filepath.Join(dir, "graph.graphml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(gml), "<node "); n != nodes+external {
+		t.Errorf("GraphML has %d nodes, expected %d", n, nodes+external)
+	}
+	if n := strings.Count(string(gml), "<edge "); n != edges {
+		t.Errorf("GraphML has %d edges, expected %d", n, edges)
+	}
+}
+
+var (
+	head     = regexp.MustCompile(`q\(([^)]*)\)`)
+	constant = regexp.MustCompile(`<([^>]*)>`)
+	class    = regexp.MustCompile(`rdf:type, ub:([A-Za-z]+)`)
+)
+
+// queries return same columns, refer to same entities and classes
+func TestQuery(t *testing.T) {
+	for i, expect := range lubm.Queries() {
+		query, err := graph.Query(i + 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, columns, _ := strings.Cut(query, "RETURN ")
+		columns = strings.TrimPrefix(columns, "DISTINCT ")
+		if a, b := len(strings.Split(columns, ",")), len(strings.Split(head.FindStringSubmatch(expect)[1], ",")); a != b {
+			t.Errorf("Query%d returns %d columns, expected %d", i+1, a, b)
+		}
+
+		for _, m := range constant.FindAllStringSubmatch(expect, -1) {
+			if iri := lubm.ToURI(curie.IRI(m[1])); !strings.Contains(query, iri) {
+				t.Errorf("Query%d does not refer %s", i+1, iri)
+			}
+		}
+
+		for _, m := range class.FindAllStringSubmatch(expect, -1) {
+			if !strings.Contains(query, ":"+m[1]) {
+				t.Errorf("Query%d does not match label %s", i+1, m[1])
+			}
+		}
+	}
+
+	if _, err := graph.Query(10); err == nil {
+		t.Errorf("unknown query is returned")
+	}
+}
@@ -0,0 +1,137 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package relational
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/lubm"
+)
+
+//
+// Queries join entity tables through foreign keys and junction tables,
+// subclasses are distinguished by the type column. Parameters are CURIEs,
+// they are expanded to IRIs and quoted as SQL literals.
+//
+
+// Query1 is SQL equivalent of lubm.Query1
+func Query1(course ...string) string {
+	return fmt.Sprintf(`SELECT s.id
+FROM student s
+JOIN takes_course t ON t.student_id = s.id
+WHERE s.type = 'GraduateStudent' AND t.course_id = %s`,
+		param(course, "edu:University0.Department0/GraduateCourse5"))
+}
+
+// Query2 is SQL equivalent of lubm.Query2
+func Query2() string {
+	return `SELECT u.id, d.id, s.id
+FROM student s
+JOIN department d ON d.id = s.member_of
+JOIN university u ON u.id = d.university_id AND u.id = s.undergraduate_degree_from
+WHERE s.type = 'GraduateStudent'`
+}
+
+// Query3 is SQL equivalent of lubm.Query3
+func Query3(author ...string) string {
+	return fmt.Sprintf(`SELECT p.id
+FROM publication p
+JOIN publication_author a ON a.publication_id = p.id
+WHERE COALESCE(a.faculty_id, a.student_id) = %s`,
+		param(author, "edu:University0.Department0/AssistantProfessor0"))
+}
+
+// Query4 is SQL equivalent of lubm.Query4
+func Query4(dept ...string) string {
+	return fmt.Sprintf(`SELECT f.id, f.name, f.email_address, f.telephone
+FROM faculty f
+WHERE f.works_for = %s`,
+		param(dept, "edu:University0.Department0"))
+}
+
+// Query5 is SQL equivalent of lubm.Query5
+func Query5(dept ...string) string {
+	return fmt.Sprintf(`SELECT s.id
+FROM student s
+WHERE s.type = 'UndergraduateStudent' AND s.member_of = %s`,
+		param(dept, "edu:University0.Department0"))
+}
+
+// Query6 is SQL equivalent of lubm.Query6
+func Query6() string {
+	return `SELECT s.id
+FROM student s
+WHERE s.type = 'UndergraduateStudent'`
+}
+
+// Query7 is SQL equivalent of lubm.Query7
+func Query7(teacher ...string) string {
+	return fmt.Sprintf(`SELECT s.id, c.id
+FROM teacher_of t
+JOIN course c ON c.id = t.course_id
+JOIN takes_course tc ON tc.course_id = c.id
+JOIN student s ON s.id = tc.student_id
+WHERE c.type = 'Course' AND s.type = 'UndergraduateStudent' AND t.faculty_id = %s`,
+		param(teacher, "edu:University0.Department0/AssistantProfessor0"))
+}
+
+// Query8 is SQL equivalent of lubm.Query8
+func Query8(university ...string) string {
+	return fmt.Sprintf(`SELECT d.id, s.id, s.email_address
+FROM department d
+JOIN student s ON s.member_of = d.id
+WHERE d.university_id = %s`,
+		param(university, "edu:University0"))
+}
+
+// Query9 is SQL equivalent of lubm.Query9
+func Query9() string {
+	return `SELECT DISTINCT s.id
+FROM student s
+JOIN teacher_of t ON t.faculty_id = s.advisor
+JOIN takes_course tc ON tc.student_id = s.id AND tc.course_id = t.course_id`
+}
+
+// queries with default parameters, query #id is at index id - 1
+func queries() []string {
+	return []string{
+		Query1(), Query2(), Query3(), Query4(), Query5(),
+		Query6(), Query7(), Query8(), Query9(),
+	}
+}
+
+// Query returns SQL query #id (starting from 1) with default parameters
+func Query(id int) (string, error) {
+	seq := queries()
+	if id < 1 || id > len(seq) {
+		return "", fmt.Errorf("unknown query #%d", id)
+	}
+	return seq[id-1], nil
+}
+
+// Queries returns all queries as a script, queries are separated by
+// semicolon and preceded by comment with the name.
+func Queries() string {
+	var sb strings.Builder
+	for i, q := range queries() {
+		fmt.Fprintf(&sb, "-- Query%d\n%s;\n\n", i+1, q)
+	}
+	return sb.String()
+}
+
+// parameter of the query or default value as SQL literal, CURIE is
+// expanded to IRI
+func param(args []string, value string) string {
+	if len(args) != 0 {
+		value = args[0]
+	}
+	return "'" + strings.ReplaceAll(lubm.ToURI(curie.IRI(value)), "'", "''") + "'"
+}
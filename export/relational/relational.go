@@ -0,0 +1,402 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+// Package relational exports the dataset as normalized relational tables.
+// Every entity is a table, multi-valued properties are junction tables.
+// Rows are identified by fully qualified IRIs of entities.
+//
+// The exporter writes into directory DDL of tables, CSV file per table and
+// SQL versions of the queries:
+//
+//	schema.sql
+//	university.csv, department.csv, ..., publication_author.csv
+//	queries.sql
+//
+// CSV files have header, the empty field is NULL. Tables are declared in
+// the order of foreign keys, they have to be loaded in the same order.
+// Degrees refer to any university of the dataset, the row of university is
+// written by its partition only, therefore tables of all partitions are
+// loaded before the next table. Universities out of the dataset have no name.
+package relational
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/lubm"
+)
+
+// column of the table
+type column struct {
+	name       string
+	primary    bool
+	nullable   bool
+	references string // table referred by foreign key
+}
+
+// table of the schema
+type table struct {
+	name    string
+	columns []column
+	check   string
+}
+
+// Schema of the dataset, tables are ordered by dependencies
+var schema = []table{
+	{
+		name: "university",
+		columns: []column{
+			{name: "id", primary: true},
+			{name: "name", nullable: true},
+		},
+	},
+	{
+		name: "department",
+		columns: []column{
+			{name: "id", primary: true},
+			{name: "name"},
+			{name: "university_id", references: "university"},
+		},
+	},
+	{
+		name: "research_group",
+		columns: []column{
+			{name: "id", primary: true},
+			{name: "department_id", references: "department"},
+		},
+	},
+	{
+		name: "course",
+		columns: []column{
+			{name: "id", primary: true},
+			{name: "type"},
+			{name: "name"},
+		},
+	},
+	{
+		name: "faculty",
+		columns: []column{
+			{name: "id", primary: true},
+			{name: "type"},
+			{name: "name"},
+			{name: "email_address"},
+			{name: "telephone"},
+			{name: "research_interest"},
+			{name: "works_for", references: "department"},
+			{name: "head_of", nullable: true, references: "department"},
+			{name: "undergraduate_degree_from", nullable: true, references: "university"},
+			{name: "masters_degree_from", nullable: true, references: "university"},
+			{name: "doctoral_degree_from", nullable: true, references: "university"},
+		},
+	},
+	{
+		name: "student",
+		columns: []column{
+			{name: "id", primary: true},
+			{name: "type"},
+			{name: "name"},
+			{name: "email_address"},
+			{name: "telephone"},
+			{name: "member_of", references: "department"},
+			{name: "undergraduate_degree_from", nullable: true, references: "university"},
+			{name: "masters_degree_from", nullable: true, references: "university"},
+			{name: "advisor", nullable: true, references: "faculty"},
+			{name: "teaching_assistant_of", nullable: true, references: "course"},
+		},
+	},
+	{
+		name: "publication",
+		columns: []column{
+			{name: "id", primary: true},
+			{name: "name"},
+		},
+	},
+	{
+		name: "teacher_of",
+		columns: []column{
+			{name: "faculty_id", primary: true, references: "faculty"},
+			{name: "course_id", primary: true, references: "course"},
+		},
+	},
+	{
+		name: "takes_course",
+		columns: []column{
+			{name: "student_id", primary: true, references: "student"},
+			{name: "course_id", primary: true, references: "course"},
+		},
+	},
+	{
+		// author is either faculty or graduate student
+		name: "publication_author",
+		columns: []column{
+			{name: "publication_id", references: "publication"},
+			{name: "faculty_id", nullable: true, references: "faculty"},
+			{name: "student_id", nullable: true, references: "student"},
+		},
+		check: "(faculty_id IS NULL) <> (student_id IS NULL)",
+	},
+}
+
+// DDL of the schema
+func DDL() string {
+	var sb strings.Builder
+
+	for _, t := range schema {
+		fmt.Fprintf(&sb, "CREATE TABLE %s (\n", t.name)
+
+		lines := []string{}
+		keys := []string{}
+		for _, c := range t.columns {
+			line := fmt.Sprintf("  %s VARCHAR(255)", c.name)
+			if !c.nullable {
+				line += " NOT NULL"
+			}
+			lines = append(lines, line)
+			if c.primary {
+				keys = append(keys, c.name)
+			}
+		}
+
+		if len(keys) > 0 {
+			lines = append(lines, fmt.Sprintf("  PRIMARY KEY (%s)", strings.Join(keys, ", ")))
+		}
+
+		for _, c := range t.columns {
+			if c.references != "" {
+				lines = append(lines, fmt.Sprintf("  FOREIGN KEY (%s) REFERENCES %s (id)", c.name, c.references))
+			}
+		}
+
+		if t.check != "" {
+			lines = append(lines, fmt.Sprintf("  CHECK (%s)", t.check))
+		}
+
+		sb.WriteString(strings.Join(lines, ",\n"))
+		sb.WriteString("\n);\n\n")
+	}
+
+	return sb.String()
+}
+
+// Writer of tables, it is lubm.Visitor
+type Writer struct {
+	dir    string
+	tables map[string]*file
+
+	maxUniversityID int
+	referenced      map[lubm.IRI]bool // referenced by degrees
+}
+
+type file struct {
+	fd  *os.File
+	csv *csv.Writer
+}
+
+// New creates writer of tables of the dataset with universities
+// [0, maxUniversityID) into the directory
+func New(dir string, maxUniversityID int) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	w := &Writer{
+		dir:             dir,
+		tables:          map[string]*file{},
+		maxUniversityID: maxUniversityID,
+		referenced:      map[lubm.IRI]bool{},
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "schema.sql"), []byte(DDL()), 0644); err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "queries.sql"), []byte(Queries()), 0644); err != nil {
+		return nil, err
+	}
+
+	for _, t := range schema {
+		fd, err := os.Create(filepath.Join(dir, t.name+".csv"))
+		if err != nil {
+			w.close()
+			return nil, err
+		}
+
+		f := &file{fd: fd, csv: csv.NewWriter(fd)}
+		w.tables[t.name] = f
+
+		header := make([]string, len(t.columns))
+		for i, c := range t.columns {
+			header[i] = c.name
+		}
+		if err := f.csv.Write(header); err != nil {
+			w.close()
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+func (w *Writer) row(table string, values ...string) error {
+	return w.tables[table].csv.Write(values)
+}
+
+func (w *Writer) University(x *lubm.University) error {
+	return w.row("university", uid(x.ID), x.Name)
+}
+
+func (w *Writer) Department(x *lubm.Department) error {
+	return w.row("department", uid(x.ID), x.Name, iri(x.SubOrganizationOf))
+}
+
+func (w *Writer) Faculty(x *lubm.Faculty) error {
+	err := w.row("faculty",
+		uid(x.ID),
+		local(x.Type),
+		x.Name,
+		x.EmailAddress,
+		x.Telephone,
+		x.ResearchInterest,
+		iri(x.WorksFor),
+		ref(x.HeadOf),
+		w.degree(x.UndergraduateDegreeFrom),
+		w.degree(x.MastersDegreeFrom),
+		w.degree(x.DoctoralDegreeFrom),
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, course := range x.TeacherOf {
+		if err := w.row("teacher_of", uid(x.ID), iri(course)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *Writer) Student(x *lubm.Student) error {
+	err := w.row("student",
+		uid(x.ID),
+		local(x.Type),
+		x.Name,
+		x.EmailAddress,
+		x.Telephone,
+		iri(x.MemberOf),
+		w.degree(x.UndergraduateDegreeFrom),
+		w.degree(x.MastersDegreeFrom),
+		ref(x.Advisor),
+		ref(x.TeachingAssistantOf),
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, course := range x.TakesCourse {
+		if err := w.row("takes_course", uid(x.ID), iri(course)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *Writer) Course(x *lubm.Course) error {
+	return w.row("course", uid(x.ID), local(x.Type), x.Name)
+}
+
+// authors of publications are either faculty or students, the kind of
+// author is denoted by its IRI
+func (w *Writer) Publication(x *lubm.Publication) error {
+	if err := w.row("publication", uid(x.ID), x.Name); err != nil {
+		return err
+	}
+
+	for _, author := range x.PublicationAuthor {
+		faculty, student := iri(author), ""
+		if kind, _ := lubm.TypeOf(author); kind == "ub:UndergraduateStudent" || kind == "ub:GraduateStudent" {
+			faculty, student = "", iri(author)
+		}
+
+		if err := w.row("publication_author", uid(x.ID), faculty, student); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *Writer) ResearchGroup(x *lubm.ResearchGroup) error {
+	return w.row("research_group", uid(x.ID), iri(x.SubOrganizationOf))
+}
+
+func (w *Writer) degree(x *lubm.IRI) string {
+	if x != nil {
+		w.referenced[*x] = true
+	}
+	return ref(x)
+}
+
+// Close writes universities referenced out of the dataset and completes
+// files.
+func (w *Writer) Close() error {
+	seq := []string{}
+	for x := range w.referenced {
+		if id, ok := lubm.UniversityOf(x); !ok || id >= w.maxUniversityID {
+			seq = append(seq, iri(x))
+		}
+	}
+	sort.Strings(seq)
+
+	for _, id := range seq {
+		if err := w.row("university", id, ""); err != nil {
+			w.close()
+			return err
+		}
+	}
+
+	return w.close()
+}
+
+// closes all files, returns the first error
+func (w *Writer) close() error {
+	var first error
+	for _, f := range w.tables {
+		f.csv.Flush()
+		if err := f.csv.Error(); err != nil && first == nil {
+			first = err
+		}
+		if err := f.fd.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	w.tables = nil
+	return first
+}
+
+func uid(x lubm.UID) string { return lubm.ToURI(curie.IRI(x)) }
+func iri(x lubm.IRI) string { return lubm.ToURI(curie.IRI(x)) }
+
+func ref(x *lubm.IRI) string {
+	if x == nil {
+		return ""
+	}
+	return iri(*x)
+}
+
+// ub:FullProfessor ⟼ FullProfessor
+func local(x lubm.UID) string {
+	_, name := curie.Seq(curie.IRI(x))
+	return name
+}
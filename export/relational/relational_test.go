@@ -0,0 +1,162 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package relational_test
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/lubm"
+	"github.com/kshard/lubm/export/relational"
+)
+
+const seed = 1683234740
+
+func rowsOf(t *testing.T, dir, table string) [][]string {
+	t.Helper()
+
+	fd, err := os.Open(filepath.Join(dir, table+".csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	rows, err := csv.NewReader(fd).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows[1:]
+}
+
+// every knowledge statement is either a column of entity row or a row of
+// junction table, type of entity is either column or implied by table
+func TestWriter(t *testing.T) {
+	bags := &lubm.Statements{}
+	if err := lubm.NewDataSet(seed, 1, nil).Visit(0, bags); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	w, err := relational.New(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lubm.NewDataSet(seed, 1, nil).Visit(0, w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	triples := 0
+	for table, typed := range map[string]bool{
+		"university": false, "department": false, "research_group": false,
+		"course": true, "faculty": true, "student": true, "publication": false,
+	} {
+		for _, row := range rowsOf(t, dir, table) {
+			// universities out of the dataset have no statements
+			if table == "university" && row[1] == "" {
+				continue
+			}
+			if !typed {
+				triples++
+			}
+			for _, value := range row[1:] {
+				if value != "" {
+					triples++
+				}
+			}
+		}
+	}
+
+	for _, table := range []string{"teacher_of", "takes_course", "publication_author"} {
+		triples += len(rowsOf(t, dir, table))
+	}
+
+	if triples != bags.Len() {
+		t.Errorf("tables have %d values, expected %d triples", triples, bags.Len())
+	}
+}
+
+var (
+	head     = regexp.MustCompile(`q\(([^)]*)\)`)
+	constant = regexp.MustCompile(`<([^>]*)>`)
+	class    = regexp.MustCompile(`rdf:type, ub:([A-Za-z]+)`)
+)
+
+// queries select same columns, refer to same entities and classes, classes
+// are either tables or types
+func TestQuery(t *testing.T) {
+	for i, expect := range lubm.Queries() {
+		query, err := relational.Query(i + 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		columns, _, _ := strings.Cut(strings.TrimPrefix(query, "SELECT "), "\nFROM")
+		columns = strings.TrimPrefix(columns, "DISTINCT ")
+		if a, b := len(strings.Split(columns, ",")), len(strings.Split(head.FindStringSubmatch(expect)[1], ",")); a != b {
+			t.Errorf("Query%d selects %d columns, expected %d", i+1, a, b)
+		}
+
+		for _, m := range constant.FindAllStringSubmatch(expect, -1) {
+			if iri := lubm.ToURI(curie.IRI(m[1])); !strings.Contains(query, "'"+iri+"'") {
+				t.Errorf("Query%d does not refer %s", i+1, iri)
+			}
+		}
+
+		for _, m := range class.FindAllStringSubmatch(expect, -1) {
+			if !strings.Contains(strings.ToLower(query), strings.ToLower(m[1])) {
+				t.Errorf("Query%d does not match class %s", i+1, m[1])
+			}
+		}
+	}
+
+	if _, err := relational.Query(10); err == nil {
+		t.Errorf("unknown query is returned")
+	}
+}
+
+// authors are classified regardless of order of visited entities, e.g.
+// publications decoded without their departments
+func TestPublicationAuthor(t *testing.T) {
+	dir := t.TempDir()
+	w, err := relational.New(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = w.Publication(&lubm.Publication{
+		ID:   "edu:University0.Department1/Lecturer0/Publication0",
+		Type: "ub:Publication",
+		Name: "Publication0",
+		PublicationAuthor: []lubm.IRI{
+			"edu:University0.Department1/Lecturer0",
+			"edu:University0.Department1/GraduateStudent3",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := rowsOf(t, dir, "publication_author")
+	if len(rows) != 2 ||
+		rows[0][1] == "" || rows[0][2] != "" ||
+		rows[1][1] != "" || rows[1][2] == "" {
+		t.Errorf("unexpected authors %v", rows)
+	}
+}
@@ -0,0 +1,155 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package lubm
+
+import (
+	"context"
+	"sort"
+
+	"github.com/kshard/lubm/internal/adapter"
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/store/ephemeral"
+	"github.com/kshard/xsd"
+)
+
+// Graph is in-memory set of knowledge statements, it is used as
+// the reference state of the dataset while applying changes.
+type Graph struct {
+	set map[triple]struct{}
+}
+
+type triple struct {
+	s, p xsd.AnyURI
+	o    xsd.Value
+}
+
+// NewGraph creates empty graph
+func NewGraph() *Graph {
+	return &Graph{set: map[triple]struct{}{}}
+}
+
+// Len returns number of knowledge statements in the graph
+func (g *Graph) Len() int { return len(g.set) }
+
+// Add knowledge statements to the graph
+func (g *Graph) Add(bag spock.Bag) {
+	for _, x := range bag {
+		g.set[triple{s: x.S, p: x.P, o: x.O}] = struct{}{}
+	}
+}
+
+// Remove knowledge statements from the graph
+func (g *Graph) Remove(bag spock.Bag) {
+	for _, x := range bag {
+		delete(g.set, triple{s: x.S, p: x.P, o: x.O})
+	}
+}
+
+// Has checks that knowledge statement belongs to the graph
+func (g *Graph) Has(x spock.SPOCK) bool {
+	_, has := g.set[triple{s: x.S, p: x.P, o: x.O}]
+	return has
+}
+
+// Bag returns knowledge statements of the graph ordered by subject,
+// predicate and object.
+func (g *Graph) Bag() spock.Bag {
+	type row struct {
+		s, p, o string
+		spock   spock.SPOCK
+	}
+
+	seq := make([]row, 0, len(g.set))
+	for t := range g.set {
+		seq = append(seq, row{
+			s:     t.s.String(),
+			p:     t.p.String(),
+			o:     literalOf(t.o),
+			spock: spock.SPOCK{S: t.s, P: t.p, O: t.o},
+		})
+	}
+
+	sort.Slice(seq, func(i, j int) bool {
+		switch {
+		case seq[i].s != seq[j].s:
+			return seq[i].s < seq[j].s
+		case seq[i].p != seq[j].p:
+			return seq[i].p < seq[j].p
+		default:
+			return seq[i].o < seq[j].o
+		}
+	})
+
+	bag := make(spock.Bag, len(seq))
+	for i, x := range seq {
+		bag[i] = x.spock
+	}
+	return bag
+}
+
+// Store loads the graph into new instance of store
+func (g *Graph) Store() *ephemeral.Store {
+	store := ephemeral.New()
+	ephemeral.Add(store, g.Bag())
+	return store
+}
+
+// Mutable is store of knowledge statements supporting removal. The
+// ephemeral.Store is append only, removed statements are kept in the store
+// as tombstones and skipped by queries, statements added after removal are
+// revived. Added statements must not belong to the store, removed must.
+type Mutable struct {
+	store   *ephemeral.Store
+	removed map[triple]struct{}
+	size    int
+}
+
+// NewMutable creates mutable store on top of the store
+func NewMutable(store *ephemeral.Store) *Mutable {
+	return &Mutable{
+		store:   store,
+		removed: map[triple]struct{}{},
+		size:    ephemeral.Size(store),
+	}
+}
+
+// Len returns number of knowledge statements in the store
+func (m *Mutable) Len() int { return m.size }
+
+// Add knowledge statements to the store
+func (m *Mutable) Add(bag spock.Bag) {
+	for _, x := range bag {
+		t := triple{s: x.S, p: x.P, o: x.O}
+		if _, has := m.removed[t]; has {
+			delete(m.removed, t)
+		} else {
+			ephemeral.Put(m.store, x)
+		}
+		m.size++
+	}
+}
+
+// Remove knowledge statements from the store
+func (m *Mutable) Remove(bag spock.Bag) {
+	for _, x := range bag {
+		m.removed[triple{s: x.S, p: x.P, o: x.O}] = struct{}{}
+		m.size--
+	}
+}
+
+// EvalContext evaluates the query over statements of the store, the
+// evaluation is aborted with context error once context is done.
+func (m *Mutable) EvalContext(ctx context.Context, query string) (*Result, error) {
+	return eval(query, adapter.NewStreamRemoved(ctx, m.store, m.isRemoved))
+}
+
+func (m *Mutable) isRemoved(x spock.SPOCK) bool {
+	_, has := m.removed[triple{s: x.S, p: x.P, o: x.O}]
+	return has
+}
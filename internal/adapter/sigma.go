@@ -9,6 +9,8 @@
 package adapter
 
 import (
+	"context"
+
 	"github.com/kshard/sigma/vm"
 	"github.com/kshard/spock"
 	"github.com/kshard/spock/store/ephemeral"
@@ -16,21 +18,39 @@ import (
 )
 
 type subQ struct {
-	addr   []vm.Addr
-	store  *ephemeral.Store
-	stream spock.Stream
+	ctx     context.Context
+	addr    []vm.Addr
+	store   *ephemeral.Store
+	removed func(spock.SPOCK) bool
+	stream  spock.Stream
 }
 
 func NewStream(store *ephemeral.Store) func(addr []vm.Addr) vm.Stream {
+	return NewStreamContext(context.Background(), store)
+}
+
+// NewStreamContext creates stream that is terminated once context is done
+func NewStreamContext(ctx context.Context, store *ephemeral.Store) func(addr []vm.Addr) vm.Stream {
+	return NewStreamRemoved(ctx, store, nil)
+}
+
+// NewStreamRemoved creates stream that skips removed knowledge statements
+func NewStreamRemoved(ctx context.Context, store *ephemeral.Store, removed func(spock.SPOCK) bool) func(addr []vm.Addr) vm.Stream {
 	return func(addr []vm.Addr) vm.Stream {
 		return &subQ{
-			addr:  addr,
-			store: store,
+			ctx:     ctx,
+			addr:    addr,
+			store:   store,
+			removed: removed,
 		}
 	}
 }
 
 func (seq *subQ) Init(heap *vm.Heap) error {
+	if err := seq.ctx.Err(); err != nil {
+		return err
+	}
+
 	var (
 		s *spock.Predicate[xsd.AnyURI]
 		p *spock.Predicate[xsd.AnyURI]
@@ -71,11 +91,22 @@ func (seq *subQ) Init(heap *vm.Heap) error {
 }
 
 func (seq *subQ) Read(heap *vm.Heap) error {
+	if err := seq.ctx.Err(); err != nil {
+		return err
+	}
+
 	if !seq.stream.Next() {
 		return vm.EndOfStream
 	}
 
 	spock := seq.stream.Head()
+	for seq.removed != nil && seq.removed(spock) {
+		if !seq.stream.Next() {
+			return vm.EndOfStream
+		}
+		spock = seq.stream.Head()
+	}
+
 	if seq.addr[0].IsWritable() {
 		heap.Put(seq.addr[0], spock.S)
 	}
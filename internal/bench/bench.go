@@ -0,0 +1,184 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+// Package bench implements runners of the benchmark
+package bench
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/kshard/lubm"
+	"github.com/kshard/spock/store/ephemeral"
+)
+
+// Case of the benchmark, the query is instantiated for every iteration.
+type Case struct {
+	Name  string
+	Query func() (string, error)
+}
+
+// Cases returns benchmark queries, parameters are either drawn from
+// generator or defaults are used if generator is not defined.
+func Cases(params *lubm.Params) []Case {
+	queries := lubm.Queries()
+	cases := make([]Case, len(queries))
+	for i, q := range queries {
+		id, q := i+1, q
+		cases[i] = Case{
+			Name:  "Query" + strconv.Itoa(id),
+			Query: func() (string, error) { return q, nil },
+		}
+
+		if params != nil && parametrized(id) {
+			cases[i].Query = func() (string, error) { return params.Query(id) }
+		}
+	}
+	return cases
+}
+
+// queries with parameters drawn by lubm.Params
+func parametrized(id int) bool {
+	switch id {
+	case 1, 3, 4, 5, 7, 8:
+		return true
+	default:
+		return false
+	}
+}
+
+// Config of the benchmark runner
+type Config struct {
+	Warmup     int
+	Iterations int
+	Timeout    time.Duration
+}
+
+// Report of the benchmark case
+type Report struct {
+	Query        string        `json:"query"`
+	Iterations   int           `json:"iterations"`
+	Errors       int           `json:"errors"`
+	Results      int           `json:"results"`
+	Min          time.Duration `json:"min"`
+	Median       time.Duration `json:"median"`
+	P95          time.Duration `json:"p95"`
+	P99          time.Duration `json:"p99"`
+	Max          time.Duration `json:"max"`
+	AllocsOp     uint64        `json:"allocs_op"`
+	BytesOp      uint64        `json:"bytes_op"`
+	Error        string        `json:"error,omitempty"`
+	WarmupErrors int           `json:"warmup_errors,omitempty"`
+	WarmupError  string        `json:"warmup_error,omitempty"`
+}
+
+// Run benchmark cases sequentially over the store
+func Run(store *ephemeral.Store, cases []Case, config Config) []Report {
+	reports := make([]Report, len(cases))
+	for i, c := range cases {
+		reports[i] = run(store, c, config)
+	}
+	return reports
+}
+
+func run(store *ephemeral.Store, c Case, config Config) Report {
+	report := Report{Query: c.Name}
+
+	for i := 0; i < config.Warmup; i++ {
+		q, err := c.Query()
+		if err == nil {
+			_, err = eval(store, q, config.Timeout)
+		}
+		if err != nil {
+			report.WarmupErrors++
+			report.WarmupError = err.Error()
+		}
+	}
+
+	// allocations are measured around evaluation of successful iterations
+	var before, after runtime.MemStats
+	var mallocs, allocated uint64
+	lat := make([]time.Duration, 0, config.Iterations)
+
+	for i := 0; i < config.Iterations; i++ {
+		q, err := c.Query()
+		if err != nil {
+			report.Errors++
+			report.Error = err.Error()
+			continue
+		}
+
+		runtime.ReadMemStats(&before)
+		t := time.Now()
+		result, err := eval(store, q, config.Timeout)
+		d := time.Since(t)
+		runtime.ReadMemStats(&after)
+		if err != nil {
+			report.Errors++
+			report.Error = err.Error()
+			continue
+		}
+		lat = append(lat, d)
+		mallocs += after.Mallocs - before.Mallocs
+		allocated += after.TotalAlloc - before.TotalAlloc
+		report.Results += result.Len()
+	}
+
+	if len(lat) > 0 {
+		report.AllocsOp = mallocs / uint64(len(lat))
+		report.BytesOp = allocated / uint64(len(lat))
+	}
+
+	return summary(report, lat)
+}
+
+// summary of latencies and results
+func summary(r Report, lat []time.Duration) Report {
+	r.Iterations = len(lat)
+	if len(lat) == 0 {
+		return r
+	}
+
+	r.Results /= len(lat)
+
+	sort.Slice(lat, func(i, j int) bool { return lat[i] < lat[j] })
+	r.Min = lat[0]
+	r.Median = Percentile(lat, 50)
+	r.P95 = Percentile(lat, 95)
+	r.P99 = Percentile(lat, 99)
+	r.Max = lat[len(lat)-1]
+
+	return r
+}
+
+func eval(store *ephemeral.Store, q string, timeout time.Duration) (*lubm.Result, error) {
+	if timeout == 0 {
+		return lubm.Eval(store, q)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return lubm.EvalContext(ctx, store, q)
+}
+
+// Percentile of sorted latencies, using nearest-rank method
+func Percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
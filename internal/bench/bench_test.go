@@ -0,0 +1,45 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package bench
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kshard/lubm"
+	"github.com/kshard/spock/store/ephemeral"
+)
+
+func TestRun(t *testing.T) {
+	q, err := lubm.Query(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every odd instance of the query fails
+	calls := 0
+	c := Case{
+		Name: "Query6",
+		Query: func() (string, error) {
+			calls++
+			if calls%2 == 1 {
+				return "", errors.New("no instance")
+			}
+			return q, nil
+		},
+	}
+
+	r := run(ephemeral.New(), c, Config{Warmup: 2, Iterations: 6})
+	if r.WarmupErrors != 1 || r.WarmupError == "" {
+		t.Errorf("warm-up errors are not reported %+v", r)
+	}
+	if r.Errors != 3 || r.Iterations != 3 || r.Error == "" {
+		t.Errorf("unexpected iterations %+v", r)
+	}
+}
@@ -0,0 +1,107 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package bench
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kshard/lubm"
+)
+
+// Results of the benchmark together with the dataset manifest
+type Results struct {
+	Dataset *lubm.Manifest `json:"dataset"`
+	Reports []Report       `json:"reports"`
+}
+
+// ReadResults reads results written as JSON
+func ReadResults(path string) (*Results, error) {
+	bin, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var results Results
+	if err := json.Unmarshal(bin, &results); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &results, nil
+}
+
+// Delta of query performance against the baseline
+type Delta struct {
+	Query    string
+	Baseline Report
+	Current  Report
+	Change   float64 // change of median latency in percents
+}
+
+// Compare results with the baseline. Results are comparable only if
+// they are produced from the same dataset.
+func Compare(baseline, current *Results) ([]Delta, error) {
+	if baseline.Dataset == nil || current.Dataset == nil {
+		return nil, errors.New("dataset manifest is required to compare results")
+	}
+
+	if err := current.Dataset.Compatible(baseline.Dataset); err != nil {
+		return nil, fmt.Errorf("datasets do not match: %w", err)
+	}
+
+	seq := map[string]Report{}
+	for _, r := range baseline.Reports {
+		seq[r.Query] = r
+	}
+
+	deltas := make([]Delta, 0, len(current.Reports))
+	for _, r := range current.Reports {
+		base, has := seq[r.Query]
+		if !has {
+			continue
+		}
+
+		delta := Delta{Query: r.Query, Baseline: base, Current: r}
+		if base.Median > 0 {
+			delta.Change = 100 * float64(r.Median-base.Median) / float64(base.Median)
+		}
+
+		deltas = append(deltas, delta)
+	}
+
+	return deltas, nil
+}
+
+// WriteCompare writes human readable comparison of results
+func WriteCompare(w io.Writer, deltas []Delta) error {
+	tab := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+
+	fmt.Fprintln(tab, "query\tbaseline\tcurrent\tdelta\tresults\t")
+	for _, d := range deltas {
+		results := "="
+		if d.Baseline.Results != d.Current.Results {
+			results = fmt.Sprintf("%d ≠ %d", d.Baseline.Results, d.Current.Results)
+		}
+
+		fmt.Fprintf(tab, "%s\t%v\t%v\t%+.1f%%\t%s\t\n",
+			d.Query,
+			d.Baseline.Median.Round(time.Microsecond),
+			d.Current.Median.Round(time.Microsecond),
+			d.Change,
+			results,
+		)
+	}
+
+	return tab.Flush()
+}
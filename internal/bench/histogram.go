@@ -0,0 +1,134 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package bench
+
+import (
+	"math/bits"
+	"sort"
+	"time"
+)
+
+// Histogram of latencies with bounded memory, long running drivers record
+// latencies into it instead of keeping every sample. Latencies below 128ns
+// are exact, others are kept in log-linear buckets, 64 buckets per power
+// of two, the relative error of percentiles is below 1/64.
+type Histogram struct {
+	counts   map[int]int
+	n        int
+	min, max time.Duration
+}
+
+// NewHistogram creates empty histogram
+func NewHistogram() *Histogram {
+	return &Histogram{counts: map[int]int{}}
+}
+
+// Add latency to histogram
+func (h *Histogram) Add(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+
+	if h.n == 0 || d < h.min {
+		h.min = d
+	}
+	if h.n == 0 || d > h.max {
+		h.max = d
+	}
+
+	h.counts[bucket(d)]++
+	h.n++
+}
+
+// Merge other histogram into this one
+func (h *Histogram) Merge(other *Histogram) {
+	if other.n == 0 {
+		return
+	}
+
+	if h.n == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	if h.n == 0 || other.max > h.max {
+		h.max = other.max
+	}
+
+	for k, c := range other.counts {
+		h.counts[k] += c
+	}
+	h.n += other.n
+}
+
+// Len returns number of latencies in histogram
+func (h *Histogram) Len() int { return h.n }
+
+// Min returns the smallest latency
+func (h *Histogram) Min() time.Duration { return h.min }
+
+// Max returns the largest latency
+func (h *Histogram) Max() time.Duration { return h.max }
+
+// Percentile of latencies, using nearest-rank method. The value is the
+// upper bound of the bucket, it never exceeds the largest latency.
+func (h *Histogram) Percentile(p int) time.Duration {
+	if h.n == 0 {
+		return 0
+	}
+
+	rank := (p*h.n + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+
+	keys := make([]int, 0, len(h.counts))
+	for k := range h.counts {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+
+	seen := 0
+	for _, k := range keys {
+		seen += h.counts[k]
+		if seen >= rank {
+			d := upper(k)
+			if d > h.max {
+				d = h.max
+			}
+			if d < h.min {
+				d = h.min
+			}
+			return d
+		}
+	}
+
+	return h.max
+}
+
+// index of the bucket
+func bucket(d time.Duration) int {
+	v := uint64(d)
+	if v < 128 {
+		return int(v)
+	}
+
+	// 7 significant bits, 64 buckets per power of two
+	e := bits.Len64(v) - 7
+	return 128 + (e-1)*64 + int(v>>e) - 64
+}
+
+// upper bound of the bucket
+func upper(k int) time.Duration {
+	if k < 128 {
+		return time.Duration(k)
+	}
+
+	e := (k-128)/64 + 1
+	m := uint64((k-128)%64 + 64)
+	return time.Duration((m+1)<<e - 1)
+}
@@ -0,0 +1,319 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package bench
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kshard/lubm"
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/store/ephemeral"
+)
+
+// MixedConfig of read/write workload. Universities [From, To) are generated
+// and streamed into the store while clients run the query suite. Clients
+// keep running for Hold after the last university is ingested, so that the
+// final phase is sampled as others.
+type MixedConfig struct {
+	Seed            int64
+	Clients         int
+	From, To        int
+	MaxUniversityID int
+	Timeout         time.Duration
+	Hold            time.Duration
+}
+
+// MixedPhase summarizes queries executed while the given number of
+// universities has been ingested into the store.
+type MixedPhase struct {
+	Universities int           `json:"universities"`
+	Triples      int           `json:"triples"`
+	Elapsed      time.Duration `json:"elapsed"`
+	PerQuery     []Report      `json:"per_query"`
+}
+
+// MixedReport of read/write workload
+type MixedReport struct {
+	Dataset  *lubm.Manifest `json:"dataset,omitempty"`
+	Clients  int            `json:"clients"`
+	Elapsed  time.Duration  `json:"elapsed"`
+	Queries  int            `json:"queries"`
+	Errors   int            `json:"errors"`
+	QpS      float64        `json:"qps"`
+	Phases   []MixedPhase   `json:"phases"`
+	Failures []string       `json:"failures,omitempty"`
+}
+
+// Mixed runs the query suite from concurrent clients while new universities
+// are ingested. It is serialized mixed load: ephemeral.Store is not safe
+// for concurrent use, therefore the store is guarded by read/write lock,
+// writer holds it per bag of knowledge statements, each query holds read
+// lock for its evaluation. Queries run concurrently with each other and
+// interleave with writes bag by bag, they never overlap with a write. The
+// load measures how latency and results change as data arrives, it does
+// not exercise concurrency of the store itself. Safety of the locking is
+// checked by running the load under the race detector.
+//
+// The data is only appended, therefore results of every query instance
+// never decrease as the store grows, parameterized cases are checked per
+// instantiated query. Violations of this property are reported as failures
+// together with errors and recovered panics.
+func Mixed(store *ephemeral.Store, cases []Case, config MixedConfig) (MixedReport, error) {
+	var (
+		mu      sync.RWMutex
+		phase   atomic.Int64
+		stopped atomic.Bool
+	)
+
+	// query instantiation shares parameter generator between clients
+	var qmu sync.Mutex
+	instance := func(c Case) (string, error) {
+		qmu.Lock()
+		defer qmu.Unlock()
+		return c.Query()
+	}
+
+	mu.RLock()
+	initial := ephemeral.Size(store)
+	mu.RUnlock()
+
+	phases := []MixedPhase{{Universities: config.From, Triples: initial}}
+	clients := make([][][]*stats, config.Clients)
+	check := newMonotonic()
+
+	t := time.Now()
+
+	var wg sync.WaitGroup
+	for client := 0; client < config.Clients; client++ {
+		wg.Add(1)
+		go func(client int) {
+			defer wg.Done()
+
+			// stats per phase per case
+			seq := [][]*stats{}
+			for i := 0; !stopped.Load(); i = (i + 1) % len(cases) {
+				q, err := instance(cases[i])
+
+				mu.RLock()
+				p := int(phase.Load())
+				triples := ephemeral.Size(store)
+				s := sample{err: err}
+				if err == nil {
+					s = execute(store, q, config.Timeout)
+				}
+				mu.RUnlock()
+
+				for len(seq) <= p {
+					st := make([]*stats, len(cases))
+					for k := range st {
+						st[k] = newStats()
+					}
+					seq = append(seq, st)
+				}
+				seq[p][i].add(s)
+
+				if s.err == nil {
+					check.add(cases[i].Name, q, triples, s.results)
+				}
+			}
+
+			clients[client] = seq
+		}(client)
+	}
+
+	//
+	// Intake
+	//
+	ch := make(chan spock.Bag)
+	done := make(chan int)
+	intake := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case bag, ok := <-ch:
+				if !ok {
+					close(intake)
+					return
+				}
+				mu.Lock()
+				ephemeral.Add(store, bag)
+				mu.Unlock()
+			case university := <-done:
+				// samples taken under read lock belong to a single phase
+				mu.Lock()
+				phases = append(phases, MixedPhase{
+					Universities: university + 1,
+					Triples:      ephemeral.Size(store),
+					Elapsed:      time.Since(t),
+				})
+				phase.Add(1)
+				mu.Unlock()
+			}
+		}
+	}()
+
+	var err error
+	ds := lubm.NewDataSet(config.Seed, config.MaxUniversityID, ch)
+	for i := config.From; i < config.To; i++ {
+		if err = ds.Generate(i); err != nil {
+			break
+		}
+		done <- i
+	}
+	close(ch)
+	<-intake
+
+	if err == nil {
+		time.Sleep(config.Hold)
+	}
+	stopped.Store(true)
+	wg.Wait()
+
+	report := MixedReport{
+		Clients: config.Clients,
+		Elapsed: time.Since(t),
+		Phases:  phases,
+	}
+
+	failures := map[string]struct{}{}
+	for p := range phases {
+		phases[p].PerQuery = make([]Report, len(cases))
+		for k, c := range cases {
+			lat := NewHistogram()
+			r := Report{Query: c.Name}
+			for _, seq := range clients {
+				if p >= len(seq) {
+					continue
+				}
+				st := seq[p][k]
+				lat.Merge(st.lat)
+				r.Results += st.results
+				r.Errors += st.errors
+				if st.err != "" {
+					r.Error = st.err
+				}
+				for failure := range st.failed {
+					failures[c.Name+": "+failure] = struct{}{}
+				}
+			}
+
+			report.Queries += lat.Len() + r.Errors
+			report.Errors += r.Errors
+			phases[p].PerQuery[k] = summaryOf(r, lat)
+		}
+	}
+
+	for _, failure := range check.failures {
+		failures[failure] = struct{}{}
+	}
+
+	for failure := range failures {
+		report.Failures = append(report.Failures, failure)
+	}
+	sort.Strings(report.Failures)
+
+	report.QpS = float64(report.Queries-report.Errors) / report.Elapsed.Seconds()
+
+	return report, err
+}
+
+// monotonic checks that number of results of query instance does not
+// decrease as the store grows. Samples arrive out of order from clients,
+// every instance keeps step function of results by size of the store,
+// only the steps are kept.
+type monotonic struct {
+	sync.Mutex
+	instances map[string][]step
+	failures  []string
+}
+
+type step struct{ triples, results int }
+
+func newMonotonic() *monotonic {
+	return &monotonic{instances: map[string][]step{}}
+}
+
+func (m *monotonic) add(name, query string, triples, results int) {
+	m.Lock()
+	defer m.Unlock()
+
+	key := name + "\x00" + query
+	seq := m.instances[key]
+
+	// first step after triples
+	i := sort.Search(len(seq), func(i int) bool { return seq[i].triples > triples })
+
+	switch {
+	case i > 0 && seq[i-1].results > results:
+		m.violation(name, seq[i-1], step{triples, results})
+		return
+	case i < len(seq) && seq[i].results < results:
+		m.violation(name, step{triples, results}, seq[i])
+		return
+	case i > 0 && seq[i-1].results == results:
+		// same step
+		return
+	}
+
+	seq = append(seq, step{})
+	copy(seq[i+1:], seq[i:])
+	seq[i] = step{triples, results}
+
+	// the next step with same results is redundant
+	if i+1 < len(seq) && seq[i+1].results == results {
+		seq = append(seq[:i+1], seq[i+2:]...)
+	}
+
+	m.instances[key] = seq
+}
+
+func (m *monotonic) violation(name string, a, b step) {
+	// failures are bounded, first ones are enough to investigate
+	if len(m.failures) >= 10 {
+		return
+	}
+
+	m.failures = append(m.failures,
+		fmt.Sprintf("%s: results decreased from %d to %d while store grew from %d to %d triples",
+			name, a.results, b.results, a.triples, b.triples))
+}
+
+// WriteMixed writes human readable report of read/write workload
+func WriteMixed(w io.Writer, report MixedReport) error {
+	_, err := fmt.Fprintf(w, "==> %d clients, serialized with ingestion: %d queries, %d errors in %v (%.2f QpS)\n",
+		report.Clients, report.Queries, report.Errors, report.Elapsed, report.QpS)
+	if err != nil {
+		return err
+	}
+
+	for _, phase := range report.Phases {
+		_, err := fmt.Fprintf(w, "==> %d universities, %d triples at %v\n",
+			phase.Universities, phase.Triples, phase.Elapsed)
+		if err != nil {
+			return err
+		}
+
+		if err := WriteTable(w, phase.PerQuery); err != nil {
+			return err
+		}
+	}
+
+	for _, failure := range report.Failures {
+		if _, err := fmt.Fprintf(w, "failure: %s\n", failure); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package bench
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// WriteTable writes human readable reports
+func WriteTable(w io.Writer, reports []Report) error {
+	tab := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+
+	fmt.Fprintln(tab, "query\titer\terr\tresults\tmin\tmedian\tp95\tp99\tmax\tallocs/op\tB/op\t")
+	for _, r := range reports {
+		fmt.Fprintf(tab, "%s\t%d\t%d\t%d\t%v\t%v\t%v\t%v\t%v\t%d\t%d\t\n",
+			r.Query, r.Iterations, r.Errors, r.Results,
+			r.Min, r.Median, r.P95, r.P99, r.Max,
+			r.AllocsOp, r.BytesOp,
+		)
+	}
+
+	if err := tab.Flush(); err != nil {
+		return err
+	}
+
+	for _, r := range reports {
+		if r.WarmupError != "" {
+			fmt.Fprintf(w, "%s warm-up failed %d times: %s\n", r.Query, r.WarmupErrors, r.WarmupError)
+		}
+		if r.Error != "" {
+			fmt.Fprintf(w, "%s failed: %s\n", r.Query, r.Error)
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes reports as JSON, latencies are in nanoseconds
+func WriteJSON(w io.Writer, reports any) error {
+	codec := json.NewEncoder(w)
+	codec.SetIndent("", "  ")
+	return codec.Encode(reports)
+}
+
+// WriteCSV writes reports as CSV, latencies are in nanoseconds
+func WriteCSV(w io.Writer, reports []Report) error {
+	codec := csv.NewWriter(w)
+
+	err := codec.Write([]string{
+		"query", "iterations", "errors", "results",
+		"min_ns", "median_ns", "p95_ns", "p99_ns", "max_ns",
+		"allocs_op", "bytes_op", "warmup_errors",
+	})
+	if err != nil {
+		return err
+	}
+
+	ns := func(d time.Duration) string { return strconv.FormatInt(d.Nanoseconds(), 10) }
+	for _, r := range reports {
+		err := codec.Write([]string{
+			r.Query,
+			strconv.Itoa(r.Iterations),
+			strconv.Itoa(r.Errors),
+			strconv.Itoa(r.Results),
+			ns(r.Min), ns(r.Median), ns(r.P95), ns(r.P99), ns(r.Max),
+			strconv.FormatUint(r.AllocsOp, 10),
+			strconv.FormatUint(r.BytesOp, 10),
+			strconv.Itoa(r.WarmupErrors),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	codec.Flush()
+	return codec.Error()
+}
@@ -0,0 +1,297 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kshard/lubm"
+	"github.com/kshard/spock/store/ephemeral"
+)
+
+// Weighted case of the query mix
+type Weighted struct {
+	Case
+	Weight int
+}
+
+// Mix assigns weights to cases, cases not listed in weights has weight 1.
+// Weights of unknown cases, negative weights and mix without any case are
+// errors.
+func Mix(cases []Case, weights map[string]int) ([]Weighted, error) {
+	known := map[string]bool{}
+	for _, c := range cases {
+		known[c.Name] = true
+	}
+
+	for name, w := range weights {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown query %s in the mix", name)
+		}
+		if w < 0 {
+			return nil, fmt.Errorf("negative weight %d of %s", w, name)
+		}
+	}
+
+	mix := make([]Weighted, 0, len(cases))
+	for _, c := range cases {
+		w, has := weights[c.Name]
+		if !has {
+			w = 1
+		}
+		if w > 0 {
+			mix = append(mix, Weighted{Case: c, Weight: w})
+		}
+	}
+
+	if len(mix) == 0 {
+		return nil, fmt.Errorf("total weight of the mix is 0")
+	}
+
+	return mix, nil
+}
+
+// ThroughputConfig of multi-client driver. The driver runs either for
+// the given duration or until each client executes given number of queries,
+// whatever comes first. At least one of them has to be defined.
+type ThroughputConfig struct {
+	Seed       int64
+	Clients    int
+	Duration   time.Duration
+	Iterations int
+	Timeout    time.Duration
+}
+
+func (config ThroughputConfig) validate() error {
+	if config.Clients <= 0 {
+		return fmt.Errorf("number of clients has to be positive, got %d", config.Clients)
+	}
+
+	if config.Duration <= 0 && config.Iterations <= 0 {
+		return fmt.Errorf("either duration or iterations has to be positive")
+	}
+
+	return nil
+}
+
+// ThroughputReport of multi-client driver
+type ThroughputReport struct {
+	Dataset  *lubm.Manifest `json:"dataset,omitempty"`
+	Clients  int            `json:"clients"`
+	Elapsed  time.Duration  `json:"elapsed"`
+	Queries  int            `json:"queries"`
+	Errors   int            `json:"errors"`
+	QpS      float64        `json:"qps"`
+	PerQuery []Report       `json:"per_query"`
+	Failures []string       `json:"failures,omitempty"`
+}
+
+// sample of single query execution
+type sample struct {
+	lat     time.Duration
+	results int
+	err     error
+}
+
+// stats of query executed by client, latencies are kept in histogram so
+// that memory is bounded regardless of the duration.
+type stats struct {
+	lat     *Histogram
+	results int
+	errors  int
+	err     string
+	failed  map[string]struct{}
+}
+
+func newStats() *stats {
+	return &stats{lat: NewHistogram(), failed: map[string]struct{}{}}
+}
+
+func (st *stats) add(s sample) {
+	if s.err != nil {
+		st.errors++
+		st.err = s.err.Error()
+		st.failed[st.err] = struct{}{}
+		return
+	}
+
+	st.lat.Add(s.lat)
+	st.results += s.results
+}
+
+// Throughput runs weighted mix of queries from concurrent clients.
+// Panics raised by queries are recovered and reported as failures, use
+// the race detector (go run -race) to observe data races.
+func Throughput(store *ephemeral.Store, mix []Weighted, config ThroughputConfig) (ThroughputReport, error) {
+	if err := config.validate(); err != nil {
+		return ThroughputReport{}, err
+	}
+
+	total := 0
+	for _, w := range mix {
+		total += w.Weight
+	}
+	if total <= 0 {
+		return ThroughputReport{}, fmt.Errorf("total weight of the mix is 0")
+	}
+
+	// query instantiation shares parameter generator between clients
+	var mu sync.Mutex
+	instance := func(c Case) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return c.Query()
+	}
+
+	deadline := time.Time{}
+	if config.Duration > 0 {
+		deadline = time.Now().Add(config.Duration)
+	}
+
+	clients := make([][]*stats, config.Clients)
+	var wg sync.WaitGroup
+
+	t := time.Now()
+	for client := 0; client < config.Clients; client++ {
+		wg.Add(1)
+		go func(client int) {
+			defer wg.Done()
+
+			rnd := rand.New(rand.NewSource(config.Seed + int64(client)))
+			seq := make([]*stats, len(mix))
+			for k := range seq {
+				seq[k] = newStats()
+			}
+
+			for i := 0; config.Iterations <= 0 || i < config.Iterations; i++ {
+				if !deadline.IsZero() && time.Now().After(deadline) {
+					break
+				}
+
+				k := pick(rnd, mix, total)
+				q, err := instance(mix[k].Case)
+				if err != nil {
+					seq[k].add(sample{err: err})
+					continue
+				}
+				seq[k].add(execute(store, q, config.Timeout))
+			}
+
+			clients[client] = seq
+		}(client)
+	}
+	wg.Wait()
+
+	report := ThroughputReport{
+		Clients:  config.Clients,
+		Elapsed:  time.Since(t),
+		PerQuery: make([]Report, len(mix)),
+	}
+
+	failures := map[string]struct{}{}
+	for k, w := range mix {
+		lat := NewHistogram()
+		r := Report{Query: w.Name}
+		for _, seq := range clients {
+			st := seq[k]
+			lat.Merge(st.lat)
+			r.Results += st.results
+			r.Errors += st.errors
+			if st.err != "" {
+				r.Error = st.err
+			}
+			for failure := range st.failed {
+				failures[w.Name+": "+failure] = struct{}{}
+			}
+		}
+
+		report.Queries += lat.Len() + r.Errors
+		report.Errors += r.Errors
+		report.PerQuery[k] = summaryOf(r, lat)
+	}
+
+	for failure := range failures {
+		report.Failures = append(report.Failures, failure)
+	}
+	sort.Strings(report.Failures)
+
+	report.QpS = float64(report.Queries-report.Errors) / report.Elapsed.Seconds()
+
+	return report, nil
+}
+
+func pick(rnd *rand.Rand, mix []Weighted, total int) int {
+	x := rnd.Intn(total)
+	for k, w := range mix {
+		if x < w.Weight {
+			return k
+		}
+		x -= w.Weight
+	}
+	return len(mix) - 1
+}
+
+func execute(store *ephemeral.Store, q string, timeout time.Duration) (s sample) {
+	defer func() {
+		if err := recover(); err != nil {
+			s.err = fmt.Errorf("panic: %v", err)
+		}
+	}()
+
+	t := time.Now()
+	result, err := eval(store, q, timeout)
+	if err != nil {
+		return sample{err: err}
+	}
+
+	return sample{lat: time.Since(t), results: result.Len()}
+}
+
+// summary of latencies kept in histogram and results
+func summaryOf(r Report, lat *Histogram) Report {
+	r.Iterations = lat.Len()
+	if lat.Len() == 0 {
+		return r
+	}
+
+	r.Results /= lat.Len()
+	r.Min = lat.Min()
+	r.Median = lat.Percentile(50)
+	r.P95 = lat.Percentile(95)
+	r.P99 = lat.Percentile(99)
+	r.Max = lat.Max()
+
+	return r
+}
+
+// WriteThroughput writes human readable report of multi-client driver
+func WriteThroughput(w io.Writer, report ThroughputReport) error {
+	_, err := fmt.Fprintf(w, "==> %d clients: %d queries, %d errors in %v (%.2f QpS)\n",
+		report.Clients, report.Queries, report.Errors, report.Elapsed, report.QpS)
+	if err != nil {
+		return err
+	}
+
+	if err := WriteTable(w, report.PerQuery); err != nil {
+		return err
+	}
+
+	for _, failure := range report.Failures {
+		if _, err := fmt.Fprintf(w, "failure: %s\n", failure); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
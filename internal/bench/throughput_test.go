@@ -0,0 +1,148 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package bench
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/kshard/spock/store/ephemeral"
+)
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram()
+	seq := []time.Duration{}
+	for i := 1; i <= 10000; i++ {
+		d := time.Duration(i*i) * time.Nanosecond
+		h.Add(d)
+		seq = append(seq, d)
+	}
+	sort.Slice(seq, func(i, j int) bool { return seq[i] < seq[j] })
+
+	if h.Len() != len(seq) || h.Min() != seq[0] || h.Max() != seq[len(seq)-1] {
+		t.Fatalf("unexpected histogram %d [%v, %v]", h.Len(), h.Min(), h.Max())
+	}
+
+	for _, p := range []int{1, 50, 95, 99, 100} {
+		expect, value := Percentile(seq, p), h.Percentile(p)
+		if value < expect || float64(value-expect) > float64(expect)/64 {
+			t.Errorf("p%d: %v, expected %v", p, value, expect)
+		}
+	}
+
+	other := NewHistogram()
+	other.Add(time.Hour)
+	h.Merge(other)
+	if h.Len() != len(seq)+1 || h.Max() != time.Hour || h.Percentile(100) != time.Hour {
+		t.Errorf("unexpected merge %d %v", h.Len(), h.Max())
+	}
+}
+
+func TestMix(t *testing.T) {
+	cases := Cases(nil)
+
+	if mix, err := Mix(cases, map[string]int{"Query1": 3, "Query2": 0}); err != nil || len(mix) != 8 {
+		t.Errorf("unexpected mix %v (%v)", mix, err)
+	}
+
+	for name, weights := range map[string]map[string]int{
+		"unknown":  {"Query10": 3},
+		"negative": {"Query1": -1},
+		"empty": {
+			"Query1": 0, "Query2": 0, "Query3": 0, "Query4": 0, "Query5": 0,
+			"Query6": 0, "Query7": 0, "Query8": 0, "Query9": 0,
+		},
+	} {
+		if _, err := Mix(cases, weights); err == nil {
+			t.Errorf("%s: mix is accepted", name)
+		}
+	}
+}
+
+func TestThroughputConfig(t *testing.T) {
+	store := ephemeral.New()
+	mix, err := Mix(Cases(nil), map[string]int{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, config := range map[string]ThroughputConfig{
+		"unbounded": {Clients: 1},
+		"clients":   {Iterations: 1},
+	} {
+		if _, err := Throughput(store, mix, config); err == nil {
+			t.Errorf("%s: config is accepted", name)
+		}
+	}
+
+	if _, err := Throughput(store, nil, ThroughputConfig{Clients: 1, Iterations: 1}); err == nil {
+		t.Errorf("empty mix is accepted")
+	}
+
+	report, err := Throughput(store, mix, ThroughputConfig{Clients: 2, Iterations: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Queries != 10 || report.Errors != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestMonotonic(t *testing.T) {
+	m := newMonotonic()
+	for _, s := range []step{{10, 1}, {30, 3}, {20, 1}, {20, 2}, {40, 3}, {5, 0}} {
+		m.add("Query1", "q", s.triples, s.results)
+	}
+	m.add("Query1", "other", 50, 0)
+
+	if len(m.failures) != 0 {
+		t.Fatalf("unexpected failures %v", m.failures)
+	}
+
+	if seq := m.instances["Query1\x00q"]; len(seq) != 4 {
+		t.Errorf("unexpected steps %v", seq)
+	}
+
+	m.add("Query1", "q", 35, 2)
+	m.add("Query1", "q", 15, 3)
+	if len(m.failures) != 2 {
+		t.Errorf("violations are not detected %v", m.failures)
+	}
+}
+
+// the load is checked under race detector, go test -race
+func TestMixed(t *testing.T) {
+	config := MixedConfig{
+		Seed:            1683234740,
+		Clients:         2,
+		From:            0,
+		To:              1,
+		MaxUniversityID: 1,
+		Timeout:         time.Minute,
+		Hold:            100 * time.Millisecond,
+	}
+
+	report, err := Mixed(ephemeral.New(), Cases(nil), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Phases) != 2 || report.Errors != 0 || len(report.Failures) != 0 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	final := 0
+	for _, r := range report.Phases[1].PerQuery {
+		final += r.Iterations
+	}
+	if final == 0 {
+		t.Errorf("final phase is not sampled")
+	}
+}
@@ -0,0 +1,276 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kshard/lubm"
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/store/ephemeral"
+)
+
+// UpdatesConfig of update workload. Changes are generated on top of
+// universities [0, Universities) and applied to the store in batches.
+type UpdatesConfig struct {
+	Seed            int64
+	Universities    int
+	MaxUniversityID int
+	Changes         int
+	Batch           int
+	Timeout         time.Duration
+}
+
+func (config UpdatesConfig) validate() error {
+	if config.Universities <= 0 || config.MaxUniversityID < config.Universities {
+		return fmt.Errorf("invalid universities %d of %d", config.Universities, config.MaxUniversityID)
+	}
+
+	if config.Changes <= 0 || config.Batch <= 0 {
+		return fmt.Errorf("invalid number of changes %d in batches of %d", config.Changes, config.Batch)
+	}
+
+	return nil
+}
+
+// UpdatesBatch summarizes batch of changes applied to the store and
+// queries evaluated after it.
+type UpdatesBatch struct {
+	Changes  int           `json:"changes"`
+	Deleted  int           `json:"deleted"`
+	Inserted int           `json:"inserted"`
+	Triples  int           `json:"triples"`
+	Apply    time.Duration `json:"apply"`
+	PerQuery []Report      `json:"per_query"`
+}
+
+// UpdatesReport of update workload
+type UpdatesReport struct {
+	Dataset  *lubm.Manifest `json:"dataset,omitempty"`
+	Changes  int            `json:"changes"`
+	PerKind  map[string]int `json:"per_kind"`
+	Elapsed  time.Duration  `json:"elapsed"`
+	Batches  []UpdatesBatch `json:"batches"`
+	Failures []string       `json:"failures,omitempty"`
+}
+
+// Updates applies changes produced by lubm.Updates to the store and checks
+// that every query answers exactly as expected after each batch.
+//
+// The store is loaded from the dataset generated independently of updates.
+// Changes are applied incrementally, deleted statements are removed from
+// the store, see lubm.Mutable. Apply time covers deletes and inserts of the
+// batch. Expected answers are derived from the entity model maintained by
+// lubm.Updates, not by the query engine. Queries are unparameterized,
+// mismatched answers, invalid changes and errors are reported as failures.
+func Updates(config UpdatesConfig) (UpdatesReport, error) {
+	if err := config.validate(); err != nil {
+		return UpdatesReport{}, err
+	}
+
+	updates, err := lubm.NewUpdates(config.Seed, config.Universities, config.MaxUniversityID)
+	if err != nil {
+		return UpdatesReport{}, err
+	}
+
+	cases := Cases(nil)
+	queries := make([]string, len(cases))
+	for i, c := range cases {
+		if queries[i], err = c.Query(); err != nil {
+			return UpdatesReport{}, err
+		}
+	}
+
+	graph, store, err := generate(config)
+	if err != nil {
+		return UpdatesReport{}, err
+	}
+
+	report := UpdatesReport{PerKind: map[string]int{}}
+	t := time.Now()
+
+	for report.Changes < config.Changes {
+		batch := UpdatesBatch{}
+
+		for ; batch.Changes < config.Batch && report.Changes < config.Changes; batch.Changes++ {
+			change, err := updates.Next()
+			if err != nil {
+				return report, err
+			}
+
+			// changes are valid for the state of the dataset
+			for _, x := range change.Delete {
+				if !graph.Has(x) {
+					report.failure("change %d (%s) deletes missing %s %s %v", change.Seq, change.Kind, x.S, x.P, x.O)
+				}
+			}
+			for _, x := range change.Insert {
+				if graph.Has(x) {
+					report.failure("change %d (%s) inserts existing %s %s %v", change.Seq, change.Kind, x.S, x.P, x.O)
+				}
+			}
+			graph.Remove(change.Delete)
+			graph.Add(change.Insert)
+
+			a := time.Now()
+			store.Remove(change.Delete)
+			store.Add(change.Insert)
+			batch.Apply += time.Since(a)
+
+			batch.Deleted += len(change.Delete)
+			batch.Inserted += len(change.Insert)
+			report.PerKind[change.Kind.String()]++
+			report.Changes++
+		}
+		batch.Triples = store.Len()
+
+		expected := updates.Expected()
+		for i, c := range cases {
+			r := Report{Query: c.Name}
+
+			q := time.Now()
+			result, err := evalMutable(store, queries[i], config.Timeout)
+			lat := time.Since(q)
+			if err != nil {
+				r.Errors, r.Error = 1, err.Error()
+				report.failure("%s after %d changes: %s", c.Name, report.Changes, err)
+				batch.PerQuery = append(batch.PerQuery, r)
+				continue
+			}
+
+			r.Results = result.Len()
+			if !equal(result, expected[i]) {
+				report.failure("%s after %d changes: %d results, expected %d",
+					c.Name, report.Changes, result.Len(), expected[i].Len())
+			}
+			batch.PerQuery = append(batch.PerQuery, summary(r, []time.Duration{lat}))
+		}
+
+		report.Batches = append(report.Batches, batch)
+	}
+
+	report.Elapsed = time.Since(t)
+	return report, nil
+}
+
+// generates universities of the workload into the store and the graph
+func generate(config UpdatesConfig) (*lubm.Graph, *lubm.Mutable, error) {
+	graph := lubm.NewGraph()
+	store := ephemeral.New()
+
+	ch := make(chan spock.Bag)
+	done := make(chan struct{})
+	go func() {
+		for bag := range ch {
+			graph.Add(bag)
+			ephemeral.Add(store, bag)
+		}
+		close(done)
+	}()
+
+	var err error
+	ds := lubm.NewDataSet(config.Seed, config.MaxUniversityID, ch)
+	for i := 0; i < config.Universities && err == nil; i++ {
+		err = ds.Generate(i)
+	}
+	close(ch)
+	<-done
+
+	if err != nil {
+		return nil, nil, err
+	}
+	return graph, lubm.NewMutable(store), nil
+}
+
+func evalMutable(store *lubm.Mutable, q string, timeout time.Duration) (*lubm.Result, error) {
+	if timeout == 0 {
+		return store.EvalContext(context.Background(), q)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return store.EvalContext(ctx, q)
+}
+
+func (report *UpdatesReport) failure(format string, args ...any) {
+	if len(report.Failures) < 10 {
+		report.Failures = append(report.Failures, fmt.Sprintf(format, args...))
+	}
+}
+
+// results are equal as bags of tuples
+func equal(a, b *lubm.Result) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
+
+	x, y := tuples(a), tuples(b)
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func tuples(result *lubm.Result) []string {
+	seq := make([]string, result.Len())
+	for i, row := range result.Rows {
+		cols := make([]string, len(row))
+		for k, v := range row {
+			cols[k] = fmt.Sprint(v)
+		}
+		seq[i] = strings.Join(cols, "\t")
+	}
+	sort.Strings(seq)
+	return seq
+}
+
+// WriteUpdates writes human readable report of update workload
+func WriteUpdates(w io.Writer, report UpdatesReport) error {
+	kinds := make([]string, 0, len(report.PerKind))
+	for kind, n := range report.PerKind {
+		kinds = append(kinds, fmt.Sprintf("%s %d", kind, n))
+	}
+	sort.Strings(kinds)
+
+	_, err := fmt.Fprintf(w, "==> %d changes (%s) in %v\n",
+		report.Changes, strings.Join(kinds, ", "), report.Elapsed)
+	if err != nil {
+		return err
+	}
+
+	seen := 0
+	for _, batch := range report.Batches {
+		seen += batch.Changes
+		_, err := fmt.Fprintf(w, "==> %d changes, -%d +%d, %d triples, applied in %v\n",
+			seen, batch.Deleted, batch.Inserted, batch.Triples, batch.Apply)
+		if err != nil {
+			return err
+		}
+
+		if err := WriteTable(w, batch.PerQuery); err != nil {
+			return err
+		}
+	}
+
+	for _, failure := range report.Failures {
+		if _, err := fmt.Fprintf(w, "failure: %s\n", failure); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package bench
+
+import (
+	"testing"
+
+	"github.com/kshard/lubm"
+	"github.com/kshard/xsd"
+)
+
+func TestUpdatesConfig(t *testing.T) {
+	for name, config := range map[string]UpdatesConfig{
+		"universities": {MaxUniversityID: 1, Changes: 1, Batch: 1},
+		"range":        {Universities: 2, MaxUniversityID: 1, Changes: 1, Batch: 1},
+		"changes":      {Universities: 1, MaxUniversityID: 1, Batch: 1},
+		"batch":        {Universities: 1, MaxUniversityID: 1, Changes: 1},
+	} {
+		if _, err := Updates(config); err == nil {
+			t.Errorf("%s: config is accepted", name)
+		}
+	}
+}
+
+func TestUpdates(t *testing.T) {
+	report, err := Updates(UpdatesConfig{
+		Seed:            1683234740,
+		Universities:    1,
+		MaxUniversityID: 1,
+		Changes:         200,
+		Batch:           80,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Failures) != 0 {
+		t.Fatalf("unexpected failures %v", report.Failures)
+	}
+
+	if report.Changes != 200 || len(report.Batches) != 3 || report.Batches[2].Changes != 40 {
+		t.Errorf("unexpected report %d changes, %d batches", report.Changes, len(report.Batches))
+	}
+
+	for _, batch := range report.Batches {
+		if len(batch.PerQuery) != 9 || batch.Deleted == 0 || batch.Inserted == 0 {
+			t.Errorf("unexpected batch %+v", batch)
+		}
+	}
+
+	// the store is in the state of reference graph after same changes
+	updates, err := lubm.NewUpdates(1683234740, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 200; i++ {
+		if _, err := updates.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := report.Batches[2].Triples; n != updates.Len() {
+		t.Errorf("store has %d triples, expected %d", n, updates.Len())
+	}
+}
+
+func TestEqual(t *testing.T) {
+	a := &lubm.Result{Rows: [][]xsd.Value{{xsd.String("a")}, {xsd.String("b")}}}
+	b := &lubm.Result{Rows: [][]xsd.Value{{xsd.String("b")}, {xsd.String("a")}}}
+	c := &lubm.Result{Rows: [][]xsd.Value{{xsd.String("a")}, {xsd.String("a")}}}
+
+	if !equal(a, b) {
+		t.Errorf("order of tuples is significant")
+	}
+
+	if equal(a, c) {
+		t.Errorf("different tuples are equal")
+	}
+}
@@ -0,0 +1,206 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+// Package endpoint implements SPARQL 1.1 Protocol query operation over
+// the store. Only SELECT queries with basic graph pattern are supported.
+//
+// See https://www.w3.org/TR/sparql11-protocol/#query-operation
+package endpoint
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kshard/lubm"
+	"github.com/kshard/lubm/sparql"
+	"github.com/kshard/spock/store/ephemeral"
+)
+
+// maximum size of query submitted by POST
+const maxQuerySize = 1 << 20
+
+// Media types of query results
+const (
+	MediaTypeJSON = "application/sparql-results+json"
+	MediaTypeCSV  = "text/csv"
+	MediaTypeTSV  = "text/tab-separated-values"
+)
+
+// Endpoint is http.Handler of SPARQL queries
+type Endpoint struct {
+	store   *ephemeral.Store
+	timeout time.Duration
+
+	// Log of served queries, queries are not logged if nil
+	Log *log.Logger
+}
+
+// New creates endpoint over the store, the store must not be modified
+// while endpoint serves queries. Zero timeout disables query timeout.
+func New(store *ephemeral.Store, timeout time.Duration) *Endpoint {
+	return &Endpoint{store: store, timeout: timeout}
+}
+
+func (ep *Endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query, status, err := queryOf(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	mediaType, err := negotiate(r.Header.Get("Accept"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotAcceptable)
+		return
+	}
+
+	rules, err := sparql.Translate(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	if ep.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ep.timeout)
+		defer cancel()
+	}
+
+	t := time.Now()
+	result, err := lubm.EvalContext(ctx, ep.store, rules)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		http.Error(w, "query timeout", http.StatusServiceUnavailable)
+		return
+	case errors.Is(err, context.Canceled):
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if ep.Log != nil {
+		ep.Log.Printf("==> %d results in %v", result.Len(), time.Since(t))
+	}
+
+	w.Header().Set("Content-Type", mediaType+"; charset=utf-8")
+	switch mediaType {
+	case MediaTypeCSV:
+		err = result.WriteCSV(w)
+	case MediaTypeTSV:
+		err = result.WriteTSV(w)
+	default:
+		err = result.WriteJSON(w)
+	}
+
+	if err != nil && ep.Log != nil {
+		ep.Log.Printf("failed to write results: %s", err)
+	}
+}
+
+// queryOf decodes query from GET, POST form or POST sparql-query request
+func queryOf(w http.ResponseWriter, r *http.Request) (string, int, error) {
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query().Get("query")
+		if query == "" {
+			return "", http.StatusBadRequest, errors.New("query parameter is required")
+		}
+		return query, http.StatusOK, nil
+
+	case http.MethodPost:
+		r.Body = http.MaxBytesReader(w, r.Body, maxQuerySize)
+
+		contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			return "", http.StatusUnsupportedMediaType, errors.New("content type is required")
+		}
+
+		switch contentType {
+		case "application/x-www-form-urlencoded":
+			if err := r.ParseForm(); err != nil {
+				return "", http.StatusBadRequest, err
+			}
+			query := r.PostForm.Get("query")
+			if query == "" {
+				return "", http.StatusBadRequest, errors.New("query parameter is required")
+			}
+			return query, http.StatusOK, nil
+
+		case "application/sparql-query":
+			query, err := io.ReadAll(r.Body)
+			if err != nil {
+				return "", http.StatusBadRequest, err
+			}
+			return string(query), http.StatusOK, nil
+
+		default:
+			return "", http.StatusUnsupportedMediaType, errors.New("unsupported content type " + contentType)
+		}
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		return "", http.StatusMethodNotAllowed, errors.New("method not allowed")
+	}
+}
+
+// negotiate selects the supported media type with the highest quality value
+// listed by client, the first listed one if quality values are equal. Media
+// types with zero quality are not acceptable.
+func negotiate(accept string) (string, error) {
+	if strings.TrimSpace(accept) == "" {
+		return MediaTypeJSON, nil
+	}
+
+	selected, quality := "", 0.0
+	for _, spec := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(spec))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, has := params["q"]; has {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		if supported := supportedOf(mediaType); supported != "" && q > quality {
+			selected, quality = supported, q
+		}
+	}
+
+	if selected != "" {
+		return selected, nil
+	}
+
+	return "", errors.New("supported media types are " + strings.Join([]string{MediaTypeJSON, MediaTypeCSV, MediaTypeTSV}, ", "))
+}
+
+// supported media type of results matching the media range
+func supportedOf(mediaRange string) string {
+	switch mediaRange {
+	case MediaTypeJSON, "application/json", "application/*", "*/*":
+		return MediaTypeJSON
+	case MediaTypeCSV, "text/*":
+		return MediaTypeCSV
+	case MediaTypeTSV:
+		return MediaTypeTSV
+	default:
+		return ""
+	}
+}
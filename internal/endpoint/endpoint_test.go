@@ -0,0 +1,136 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package endpoint
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/store/ephemeral"
+	"github.com/kshard/xsd"
+)
+
+const query = `SELECT ?x WHERE { ?x a ub:GraduateStudent }`
+
+func server(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	store := ephemeral.New()
+	ephemeral.Add(store, spock.Bag{
+		{
+			S: xsd.ToAnyURI("edu:University0.Department0/GraduateStudent0"),
+			P: xsd.ToAnyURI("rdf:type"),
+			O: xsd.ToAnyURI("ub:GraduateStudent"),
+		},
+		{
+			S: xsd.ToAnyURI("edu:University0.Department0/GraduateStudent1"),
+			P: xsd.ToAnyURI("rdf:type"),
+			O: xsd.ToAnyURI("ub:GraduateStudent"),
+		},
+	})
+
+	ts := httptest.NewServer(New(store, 0))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestQuery(t *testing.T) {
+	ts := server(t)
+
+	for name, req := range map[string]func() (*http.Response, error){
+		"get": func() (*http.Response, error) {
+			return http.Get(ts.URL + "?query=" + url.QueryEscape(query))
+		},
+		"form": func() (*http.Response, error) {
+			return http.PostForm(ts.URL, url.Values{"query": {query}})
+		},
+		"sparql-query": func() (*http.Response, error) {
+			return http.Post(ts.URL, "application/sparql-query", strings.NewReader(query))
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r, err := req()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Body.Close()
+
+			if r.StatusCode != http.StatusOK || !strings.HasPrefix(r.Header.Get("Content-Type"), MediaTypeJSON) {
+				t.Fatalf("unexpected response %s %s", r.Status, r.Header.Get("Content-Type"))
+			}
+
+			var results struct {
+				Results struct {
+					Bindings []map[string]any `json:"bindings"`
+				} `json:"results"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&results); err != nil {
+				t.Fatal(err)
+			}
+			if len(results.Results.Bindings) != 2 {
+				t.Errorf("unexpected results %v", results)
+			}
+		})
+	}
+}
+
+func TestQueryFails(t *testing.T) {
+	ts := server(t)
+
+	for name, tc := range map[string]struct {
+		query  string
+		accept string
+		status int
+	}{
+		"unsupported query":  {`SELECT ?x WHERE { ?x ub:name ?n FILTER (?n = "x") }`, "", http.StatusBadRequest},
+		"invalid query":      {`SELECT`, "", http.StatusBadRequest},
+		"unsupported accept": {query, "image/png", http.StatusNotAcceptable},
+		"zero quality":       {query, "text/csv;q=0", http.StatusNotAcceptable},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, ts.URL+"?query="+url.QueryEscape(tc.query), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+
+			r, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+
+			if r.StatusCode != tc.status {
+				t.Errorf("unexpected status %s, expected %d", r.Status, tc.status)
+			}
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	for accept, expect := range map[string]string{
+		"":                                    MediaTypeJSON,
+		"*/*":                                 MediaTypeJSON,
+		"text/tab-separated-values, text/csv": MediaTypeTSV,
+		"application/sparql-results+json;q=0.5, text/csv":          MediaTypeCSV,
+		"text/csv;q=0.2, application/json;q=0.8, text/*;q=0.1":     MediaTypeJSON,
+		"image/png, text/tab-separated-values;q=0.3, text/csv;q=0": MediaTypeTSV,
+	} {
+		if mediaType, err := negotiate(accept); err != nil || mediaType != expect {
+			t.Errorf("%q: %s (%v), expected %s", accept, mediaType, err, expect)
+		}
+	}
+}
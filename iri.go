@@ -0,0 +1,131 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package lubm
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/fogfish/curie"
+)
+
+// Namespaces of the ontology used by the dataset
+var Namespaces = curie.Namespaces{
+	"rdf": "http://www.w3.org/1999/02/22-rdf-syntax-ns#",
+	"ub":  "http://www.lehigh.edu/~zhp2/2004/0401/univ-bench.owl#",
+}
+
+// ToURI expands CURIE used by the dataset into fully qualified URI.
+// The entities of universities are denoted by `edu:` prefix, the reference
+// encodes the host name in reverse order, as defined by the original generator:
+//
+//	edu:University0 ⟼ http://www.University0.edu
+//	edu:University0.Department0/Course0 ⟼ http://www.Department0.University0.edu/Course0
+func ToURI(iri curie.IRI) string {
+	prefix, ref := curie.Seq(iri)
+	if prefix != "edu" {
+		return curie.URI(Namespaces, iri)
+	}
+
+	host, path := ref, ""
+	if n := strings.IndexRune(ref, '/'); n != -1 {
+		host, path = ref[:n], ref[n:]
+	}
+
+	return "http://www." + reverseHost(host) + ".edu" + path
+}
+
+// Alternative locations of the ontology found in the wild
+var aliases = map[string]string{
+	"http://swat.cse.lehigh.edu/onto/univ-bench.owl#": "ub",
+}
+
+// FromURI compacts fully qualified URI into CURIE used by the dataset,
+// it is an inverse of ToURI. The URI is returned as-is if no prefix is known.
+//
+//	http://www.Department0.University0.edu/Course0 ⟼ edu:University0.Department0/Course0
+func FromURI(uri string) curie.IRI {
+	for prefix, ns := range Namespaces {
+		if strings.HasPrefix(uri, ns) {
+			return curie.IRI(prefix + ":" + uri[len(ns):])
+		}
+	}
+
+	for ns, prefix := range aliases {
+		if strings.HasPrefix(uri, ns) {
+			return curie.IRI(prefix + ":" + uri[len(ns):])
+		}
+	}
+
+	const (
+		scheme = "http://www."
+		domain = ".edu"
+	)
+
+	if !strings.HasPrefix(uri, scheme) {
+		return curie.IRI(uri)
+	}
+
+	host, path := uri[len(scheme):], ""
+	if n := strings.IndexRune(host, '/'); n != -1 {
+		host, path = host[:n], host[n:]
+	}
+
+	if !strings.HasSuffix(host, domain) || len(host) == len(domain) {
+		return curie.IRI(uri)
+	}
+
+	return curie.IRI("edu:" + reverseHost(host[:len(host)-len(domain)]) + path)
+}
+
+// University0.Department0 ⟼ Department0.University0
+func reverseHost(host string) string {
+	seq := strings.Split(host, ".")
+	for i, j := 0, len(seq)-1; i < j; i, j = i+1, j-1 {
+		seq[i], seq[j] = seq[j], seq[i]
+	}
+
+	return strings.Join(seq, ".")
+}
+
+// UniversityOf returns identity of the university denoted by IRI,
+// e.g. edu:University7 ⟼ 7
+func UniversityOf(iri IRI) (int, bool) {
+	prefix, ref := curie.Seq(curie.IRI(iri))
+	name, ok := strings.CutPrefix(ref, "University")
+	if prefix != "edu" || !ok {
+		return 0, false
+	}
+
+	id, err := strconv.Atoi(name)
+	if err != nil || id < 0 || strconv.Itoa(id) != name {
+		return 0, false
+	}
+	return id, true
+}
+
+// TypeOf returns type of entity denoted by IRI of the dataset,
+// e.g. edu:University0.Department0/Lecturer3 ⟼ ub:Lecturer
+func TypeOf(iri IRI) (UID, bool) {
+	prefix, ref := curie.Seq(curie.IRI(iri))
+	if prefix != "edu" {
+		return "", false
+	}
+
+	name := ref
+	if i := strings.LastIndexAny(ref, "./"); i >= 0 {
+		name = ref[i+1:]
+	}
+
+	kind := strings.TrimRight(name, "0123456789")
+	if kind == "" || kind == name {
+		return "", false
+	}
+	return UID("ub:" + kind), true
+}
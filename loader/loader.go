@@ -0,0 +1,414 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+// Package loader streams pre-generated datasets into the store or any other
+// sink. N-Triples, JSON-LD, binary snapshot and dictionary-encoded files
+// are supported, optionally compressed with gzip. The loader writes bags of
+// knowledge statements into channel, same as DataSet does while generating
+// the dataset.
+package loader
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kshard/lubm"
+	"github.com/kshard/lubm/encoding/dictionary"
+	"github.com/kshard/lubm/encoding/jsonld"
+	"github.com/kshard/lubm/encoding/ntriples"
+	"github.com/kshard/lubm/encoding/snapshot"
+	"github.com/kshard/spock"
+)
+
+// Format of the file
+type Format int
+
+const (
+	// N-Triples, one statement per line
+	NTriples Format = iota
+	// Sequence of JSON-LD documents, e.g. JSON Lines. Documents are either
+	// node object, array of node objects or @graph, see package
+	// encoding/jsonld for supported contexts.
+	JSONLD
+	// Binary snapshot, see package encoding/snapshot
+	Snapshot
+	// Dictionary-encoded statements, see package encoding/dictionary
+	Dictionary
+)
+
+func (f Format) String() string {
+	switch f {
+	case NTriples:
+		return "ntriples"
+	case JSONLD:
+		return "jsonld"
+	case Snapshot:
+		return "snapshot"
+	case Dictionary:
+		return "dictionary"
+	default:
+		return "unknown"
+	}
+}
+
+// FormatOf detects format from the file extension, compression suffix .gz
+// is ignored.
+func FormatOf(path string) (Format, error) {
+	ext := filepath.Ext(strings.TrimSuffix(path, ".gz"))
+	switch ext {
+	case ".nt":
+		return NTriples, nil
+	case ".jsonld", ".json", ".jsonl", ".ndjson":
+		return JSONLD, nil
+	case ".snap":
+		return Snapshot, nil
+	case ".dict":
+		return Dictionary, nil
+	default:
+		return 0, fmt.Errorf("unknown format of %s", path)
+	}
+}
+
+// Progress of loading the file
+type Progress struct {
+	Path    string
+	Triples int
+	Bytes   int64 // bytes read from the file, compressed if file is gzip
+	Size    int64 // size of the file, 0 if unknown
+	Elapsed time.Duration
+}
+
+// Rate of loading, triples per second
+func (p Progress) Rate() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Triples) / p.Elapsed.Seconds()
+}
+
+// ETA of loading the file, it is estimated by bytes read. It is 0 if size of
+// the file is unknown or the file is loaded.
+func (p Progress) ETA() time.Duration {
+	if p.Size == 0 || p.Bytes == 0 || p.Bytes >= p.Size {
+		return 0
+	}
+	return time.Duration(float64(p.Elapsed) * float64(p.Size-p.Bytes) / float64(p.Bytes))
+}
+
+// Number of statements in the bag written to sink
+const batchSize = 1024
+
+// Interval between progress reports
+const progressInterval = time.Second
+
+// Loader of datasets
+type Loader struct {
+	writer   chan<- spock.Bag
+	progress func(Progress)
+}
+
+// New creates loader, the progress callback is optional. It is called
+// periodically and once the file is loaded.
+func New(writer chan<- spock.Bag, progress func(Progress)) *Loader {
+	return &Loader{writer: writer, progress: progress}
+}
+
+// Load the file, format is detected by extension and compression by content.
+// It returns number of loaded statements.
+func (loader *Loader) Load(path string) (int, error) {
+	format, err := FormatOf(path)
+	if err != nil {
+		return 0, err
+	}
+
+	fd, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer fd.Close()
+
+	size := int64(0)
+	if fi, err := fd.Stat(); err == nil {
+		size = fi.Size()
+	}
+
+	counter := &countingReader{r: fd}
+	r, err := decompress(counter)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+
+	n, err := loader.read(r, format, func(triples int) Progress {
+		return Progress{Path: path, Triples: triples, Bytes: counter.n, Size: size}
+	})
+	if err != nil {
+		return n, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return n, nil
+}
+
+// LoadFiles loads files in parallel using given number of workers,
+// the progress callback is called concurrently by workers. It returns
+// number of loaded statements.
+func (loader *Loader) LoadFiles(paths []string, workers int) (int, error) {
+	_, size, err := loader.LoadFilesContext(context.Background(), paths, workers)
+	return size, err
+}
+
+// LoadFilesContext loads files as LoadFiles does until the context is done.
+// Files being loaded are completed, remaining files are not loaded and the
+// context error is returned. It returns completely loaded files, in order
+// of paths, and number of loaded statements.
+func (loader *Loader) LoadFilesContext(ctx context.Context, paths []string, workers int) ([]string, int, error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		mu     sync.Mutex
+		size   int
+		first  error
+		loaded = make([]bool, len(paths))
+		wg     sync.WaitGroup
+	)
+
+	queue := make(chan int)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				n, err := loader.Load(paths[i])
+
+				mu.Lock()
+				size += n
+				loaded[i] = err == nil
+				if err != nil && first == nil {
+					first = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for i := range paths {
+		mu.Lock()
+		failed := first != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+
+		select {
+		case queue <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	files := []string{}
+	for i, path := range paths {
+		if loaded[i] {
+			files = append(files, path)
+		}
+	}
+
+	if first == nil && len(files) < len(paths) {
+		first = ctx.Err()
+	}
+
+	return files, size, first
+}
+
+// LoadManifest loads files listed in the manifest in parallel, paths of
+// files are relative to the manifest.
+func (loader *Loader) LoadManifest(path string, workers int) (*lubm.Manifest, int, error) {
+	manifest, err := lubm.ReadManifest(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	paths := make([]string, len(manifest.Files))
+	for i, file := range manifest.Files {
+		paths[i] = filepath.Join(filepath.Dir(path), file.Path)
+	}
+
+	n, err := loader.LoadFiles(paths, workers)
+	return manifest, n, err
+}
+
+// Read dataset of given format from the reader. It returns number of
+// loaded statements.
+func (loader *Loader) Read(r io.Reader, format Format) (int, error) {
+	counter := &countingReader{r: r}
+	return loader.read(counter, format, func(triples int) Progress {
+		return Progress{Triples: triples, Bytes: counter.n}
+	})
+}
+
+func (loader *Loader) read(r io.Reader, format Format, status func(int) Progress) (int, error) {
+	var decode func(func(spock.Bag)) error
+
+	switch format {
+	case NTriples:
+		decode = func(emit func(spock.Bag)) error { return readNTriples(r, emit) }
+	case JSONLD:
+		decode = func(emit func(spock.Bag)) error { return readJSONLD(r, emit) }
+	case Snapshot:
+		decode = func(emit func(spock.Bag)) error { return readSnapshot(r, emit) }
+	case Dictionary:
+		decode = func(emit func(spock.Bag)) error { return readDictionary(r, emit) }
+	default:
+		return 0, fmt.Errorf("format %s is not supported", format)
+	}
+
+	t := time.Now()
+	reported := t
+	size := 0
+
+	report := func() {
+		if loader.progress != nil {
+			p := status(size)
+			p.Elapsed = time.Since(t)
+			loader.progress(p)
+		}
+	}
+
+	err := decode(func(bag spock.Bag) {
+		loader.writer <- bag
+		size += len(bag)
+
+		if time.Since(reported) >= progressInterval {
+			reported = time.Now()
+			report()
+		}
+	})
+	if err != nil {
+		return size, err
+	}
+
+	report()
+
+	return size, nil
+}
+
+func readNTriples(r io.Reader, emit func(spock.Bag)) error {
+	codec := ntriples.NewDecoder(r)
+	for {
+		bag := make(spock.Bag, batchSize)
+		n, err := codec.Read(bag)
+		if n > 0 {
+			emit(bag[:n])
+		}
+
+		switch {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		}
+	}
+}
+
+func readJSONLD(r io.Reader, emit func(spock.Bag)) error {
+	codec := jsonld.NewDecoder(r)
+	for {
+		bag, err := codec.Decode()
+		switch {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		}
+
+		for i := 0; i < len(bag); i += batchSize {
+			emit(bag[i:min(i+batchSize, len(bag))])
+		}
+	}
+}
+
+func readSnapshot(r io.Reader, emit func(spock.Bag)) error {
+	codec, err := snapshot.NewDecoder(r)
+	if err != nil {
+		return err
+	}
+
+	for {
+		bag, err := codec.Decode()
+		switch {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		}
+		emit(bag)
+	}
+}
+
+func readDictionary(r io.Reader, emit func(spock.Bag)) error {
+	codec := dictionary.NewDecoder(r)
+	for {
+		bag := make(spock.Bag, batchSize)
+		n, err := codec.Read(bag)
+		if n > 0 {
+			emit(bag[:n])
+		}
+
+		switch {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		}
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// decompress gzip stream, detected by magic number
+func decompress(r io.Reader) (io.Reader, error) {
+	buf := bufio.NewReaderSize(r, 64*1024)
+	magic, err := buf.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(buf)
+	}
+
+	return buf, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
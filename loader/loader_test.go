@@ -0,0 +1,182 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package loader_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kshard/lubm"
+	"github.com/kshard/lubm/encoding/jsonld"
+	"github.com/kshard/lubm/encoding/ntriples"
+	"github.com/kshard/lubm/loader"
+	"github.com/kshard/spock"
+)
+
+// bags of the single university dataset
+func dataset(t *testing.T) []spock.Bag {
+	t.Helper()
+
+	bags := &lubm.Statements{}
+	if err := lubm.NewDataSet(1683234740, 1, nil).Visit(0, bags); err != nil {
+		t.Fatal(err)
+	}
+	return bags.Bags
+}
+
+// reads the dataset with loader into graph
+func read(r func(*loader.Loader) (int, error)) (*lubm.Graph, int, error) {
+	graph := lubm.NewGraph()
+	ch := make(chan spock.Bag)
+	done := make(chan struct{})
+	go func() {
+		for bag := range ch {
+			graph.Add(bag)
+		}
+		close(done)
+	}()
+
+	n, err := r(loader.New(ch, nil))
+	close(ch)
+	<-done
+
+	return graph, n, err
+}
+
+func equal(t *testing.T, bags []spock.Bag, graph *lubm.Graph, n int) {
+	t.Helper()
+
+	expect := lubm.NewGraph()
+	size := 0
+	for _, bag := range bags {
+		expect.Add(bag)
+		size += len(bag)
+	}
+
+	if n != size || graph.Len() != expect.Len() {
+		t.Fatalf("loaded %d triples (%d unique), expected %d (%d unique)", n, graph.Len(), size, expect.Len())
+	}
+
+	for _, x := range expect.Bag() {
+		if !graph.Has(x) {
+			t.Fatalf("missing %s %s %v", x.S, x.P, x.O)
+		}
+	}
+}
+
+func TestNTriples(t *testing.T) {
+	bags := dataset(t)
+
+	buf := &bytes.Buffer{}
+	enc := ntriples.NewEncoder(buf)
+	for _, bag := range bags {
+		if err := enc.Encode(bag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	graph, n, err := read(func(ld *loader.Loader) (int, error) {
+		return ld.Read(bytes.NewReader(buf.Bytes()), loader.NTriples)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	equal(t, bags, graph, n)
+}
+
+func TestJSONLD(t *testing.T) {
+	bags := dataset(t)
+
+	buf := &bytes.Buffer{}
+	enc := jsonld.NewEncoder(buf)
+	for _, bag := range bags {
+		if err := enc.Encode(bag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// loaded from compressed file
+	path := filepath.Join(t.TempDir(), "lubm.jsonld.gz")
+	fd, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(fd)
+	if _, err := gz.Write(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := fd.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	graph, n, err := read(func(ld *loader.Loader) (int, error) { return ld.Load(path) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	equal(t, bags, graph, n)
+}
+
+// documents produced elsewhere have no context or are arrays of nodes
+func TestJSONLDNodes(t *testing.T) {
+	const (
+		university = `{"@id":"http://www.University0.edu","@type":"ub:University","ub:name":"University0"}`
+		department = `{"@id":"http://www.Department0.University0.edu","@type":"ub:Department","ub:subOrganizationOf":{"@id":"http://www.University0.edu"}}`
+	)
+
+	for name, doc := range map[string]string{
+		"node":  university + "\n" + department,
+		"array": "[" + university + "," + department + "]",
+		"graph": `{"@graph":[` + university + "," + department + `]}`,
+	} {
+		graph, n, err := read(func(ld *loader.Loader) (int, error) {
+			return ld.Read(strings.NewReader(doc), loader.JSONLD)
+		})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		if n != 4 || graph.Len() != 4 {
+			t.Errorf("%s: loaded %d triples, expected 4", name, n)
+		}
+	}
+}
+
+func TestJSONLDContext(t *testing.T) {
+	const node = `"@graph":[{"@id":"http://www.University0.edu","@type":"ub:University","ub:name":"University0"}]`
+
+	for name, doc := range map[string]string{
+		"prefix": `{"@context":{"ub":"http://example.com/onto#"},` + node + `}`,
+		"vocab":  `{"@context":{"@vocab":"http://swat.cse.lehigh.edu/onto/univ-bench.owl#"},` + node + `}`,
+		"remote": `{"@context":"http://example.com/context.jsonld",` + node + `}`,
+	} {
+		_, _, err := read(func(ld *loader.Loader) (int, error) {
+			return ld.Read(strings.NewReader(doc), loader.JSONLD)
+		})
+		if !errors.Is(err, jsonld.ErrContext) {
+			t.Errorf("%s: context is accepted (%v)", name, err)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package lubm
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kshard/spock"
+	"github.com/kshard/xsd"
+)
+
+// Profile of the generator, ranges of entities follows the original UBA
+const Profile = "uba"
+
+// Manifest describes how the dataset is generated. The partial dataset is
+// interrupted generation, it contains complete universities [From, To) only.
+// The dataset loaded from files is partial if loading is interrupted, it
+// contains complete Sources only.
+type Manifest struct {
+	Seed            int64             `json:"seed"`
+	From            int               `json:"from"`
+	To              int               `json:"to"`
+	MaxUniversityID int               `json:"max_university_id"`
+	Partition       string            `json:"partition,omitempty"`
+	Partial         bool              `json:"partial,omitempty"`
+	Profile         string            `json:"profile"`
+	Formats         []string          `json:"formats,omitempty"`
+	Modules         map[string]string `json:"modules"`
+	Files           []File            `json:"files,omitempty"`
+	Sources         []string          `json:"sources,omitempty"`
+	Triples         int               `json:"triples"`
+	Digest          string            `json:"digest"`
+	Started         time.Time         `json:"started"`
+	Elapsed         time.Duration     `json:"elapsed"`
+}
+
+// File of the dataset
+type File struct {
+	Path    string `json:"path"`
+	Format  string `json:"format"`
+	Triples int    `json:"triples"`
+	Bytes   int64  `json:"bytes"`
+	SHA256  string `json:"sha256"`
+}
+
+// NewManifest creates manifest of universities [from, to)
+func NewManifest(seed int64, from, to, maxUniversityID int) *Manifest {
+	return &Manifest{
+		Seed:            seed,
+		From:            from,
+		To:              to,
+		MaxUniversityID: maxUniversityID,
+		Profile:         Profile,
+		Modules:         Modules(),
+		Started:         time.Now().UTC(),
+	}
+}
+
+// Modules returns versions of modules linked into the binary
+func Modules() map[string]string {
+	modules := map[string]string{}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return modules
+	}
+
+	for _, dep := range info.Deps {
+		if strings.HasPrefix(dep.Path, "github.com/kshard/") {
+			modules[dep.Path] = dep.Version
+		}
+	}
+
+	// the module is either the main module or dependency of it,
+	// development builds are identified by the revision
+	if _, has := modules[module]; !has {
+		version := info.Main.Version
+		if version == "" || version == "(devel)" {
+			for _, s := range info.Settings {
+				switch {
+				case s.Key == "vcs.revision":
+					version += "+" + s.Value
+				case s.Key == "vcs.modified" && s.Value == "true":
+					version += "+dirty"
+				}
+			}
+		}
+		modules[module] = version
+	}
+
+	return modules
+}
+
+const module = "github.com/kshard/lubm"
+
+// Compatible checks that manifests describe the same dataset. The content
+// is compared using digest, versions of modules are not compared, the store
+// and the query engine are subject of the benchmark.
+func (m *Manifest) Compatible(other *Manifest) error {
+	switch {
+	case m.Profile != other.Profile:
+		return fmt.Errorf("profile %s does not match %s", m.Profile, other.Profile)
+	case m.Seed != other.Seed:
+		return fmt.Errorf("seed %d does not match %d", m.Seed, other.Seed)
+	case m.From != other.From || m.To != other.To:
+		return fmt.Errorf("universities [%d, %d) do not match [%d, %d)", m.From, m.To, other.From, other.To)
+	case m.Partial != other.Partial:
+		return fmt.Errorf("partial dataset does not match complete one")
+	case m.Partition != other.Partition:
+		return fmt.Errorf("partition %s does not match %s", m.Partition, other.Partition)
+	case m.MaxUniversityID != other.MaxUniversityID:
+		return fmt.Errorf("max university id %d does not match %d", m.MaxUniversityID, other.MaxUniversityID)
+	case m.Triples != other.Triples:
+		return fmt.Errorf("number of triples %d does not match %d", m.Triples, other.Triples)
+	case m.Digest != other.Digest:
+		return fmt.Errorf("digest %s does not match %s", m.Digest, other.Digest)
+	}
+
+	return nil
+}
+
+// WriteFile writes manifest as JSON
+func (m *Manifest) WriteFile(path string) error {
+	bin, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(bin, '\n'), 0644)
+}
+
+// ReadManifest reads manifest from JSON file
+func ReadManifest(path string) (*Manifest, error) {
+	bin, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(bin, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &m, nil
+}
+
+// Digest is order independent fingerprint of knowledge statements,
+// the same dataset has the same digest regardless of the file format.
+type Digest struct {
+	sum uint64
+}
+
+// Add knowledge statements to the digest
+func (d *Digest) Add(bag spock.Bag) {
+	h := fnv.New64a()
+	for _, x := range bag {
+		h.Reset()
+		h.Write([]byte(x.S.String()))
+		h.Write([]byte{0})
+		h.Write([]byte(x.P.String()))
+		h.Write([]byte{0})
+		switch o := x.O.(type) {
+		case xsd.AnyURI:
+			h.Write([]byte(o.String()))
+		default:
+			h.Write([]byte(literalOf(o)))
+			h.Write([]byte{1})
+		}
+		d.sum += h.Sum64()
+	}
+}
+
+func (d Digest) String() string { return fmt.Sprintf("%016x", d.sum) }
+
+// ParseDigest restores digest from its string form, the digest is additive
+// and continues to accumulate statements.
+func ParseDigest(s string) (Digest, error) {
+	sum, err := strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		return Digest{}, fmt.Errorf("invalid digest %s: %w", s, err)
+	}
+	return Digest{sum: sum}, nil
+}
+
+// ManifestOf returns path of manifest of the dataset file
+func ManifestOf(path string) string { return path + ".manifest.json" }
@@ -0,0 +1,93 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kshard/lubm"
+)
+
+// Checkpoint records universities completely written into shards. The
+// generation is resumed from the next university, universities are seeded
+// independently so the resumed output is identical to uninterrupted one.
+type Checkpoint struct {
+	Seed            int64       `json:"seed"`
+	From            int         `json:"from"`
+	To              int         `json:"to"`
+	MaxUniversityID int         `json:"max_university_id"`
+	Next            int         `json:"next"`
+	Files           []lubm.File `json:"files"`
+	Triples         int         `json:"triples"`
+	Digest          string      `json:"digest"`
+}
+
+// CheckpointOf returns path of checkpoint of the dataset file
+func CheckpointOf(path string) string { return path + ".checkpoint.json" }
+
+// WriteFile writes checkpoint atomically, the previous checkpoint is kept
+// if writing fails.
+func (c *Checkpoint) WriteFile(path string) error {
+	bin, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(bin, '\n'), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// ReadCheckpoint reads checkpoint and verifies that files it lists exist
+// with the recorded size. Paths of files are relative to the checkpoint.
+func ReadCheckpoint(path string) (*Checkpoint, error) {
+	bin, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Checkpoint
+	if err := json.Unmarshal(bin, &c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, file := range c.Files {
+		fi, err := os.Stat(filepath.Join(filepath.Dir(path), file.Path))
+		if err != nil {
+			return nil, err
+		}
+		if fi.Size() != file.Bytes {
+			return nil, fmt.Errorf("%s: size %d does not match checkpoint %d", file.Path, fi.Size(), file.Bytes)
+		}
+	}
+
+	return &c, nil
+}
+
+// Resume writing of the dataset after the checkpoint, the writer has to
+// shard the dataset by university.
+func (w *Writer) Resume(c *Checkpoint) error {
+	if w.config.Sharding != University {
+		return fmt.Errorf("resume requires sharding by university")
+	}
+
+	if w.file != nil || len(w.files) != 0 {
+		return fmt.Errorf("resume requires writer that has not written yet")
+	}
+
+	w.files = append(w.files, c.Files...)
+	w.university = c.Next
+	return nil
+}
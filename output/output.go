@@ -0,0 +1,318 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+// Package output writes the dataset into files, either single file or
+// shards split by university or by triple budget. The format is defined by
+// extension of the file (see loader.FormatOf), the content is compressed
+// with gzip if the name ends with .gz.
+//
+// Shards are named after the dataset file, the index of shard is inserted
+// before the extension. The index is the university for shards split by
+// university, and sequence number otherwise:
+//
+//	lubm.nt.gz ⟼ lubm.00000.nt.gz, lubm.00001.nt.gz, ...
+package output
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/kshard/lubm"
+	"github.com/kshard/lubm/encoding/dictionary"
+	"github.com/kshard/lubm/encoding/jsonld"
+	"github.com/kshard/lubm/encoding/ntriples"
+	"github.com/kshard/lubm/encoding/snapshot"
+	"github.com/kshard/lubm/loader"
+	"github.com/kshard/spock"
+)
+
+// Sharding strategy of the dataset
+type Sharding int
+
+const (
+	// Single file
+	None Sharding = iota
+	// One shard per university
+	University
+	// Shards of the fixed triple budget, bags are not split between
+	// shards therefore shards are slightly larger than the budget.
+	Triples
+)
+
+// Config of the output
+type Config struct {
+	Path     string
+	Sharding Sharding
+	Budget   int
+	Header   snapshot.Header
+}
+
+// Writer of the dataset
+type Writer struct {
+	config     Config
+	format     loader.Format
+	file       *encoder
+	shard      int
+	university int
+	files      []lubm.File
+	bytes      atomic.Int64
+	dict       *dictionary.Dictionary
+}
+
+// New creates writer of the dataset
+func New(config Config) (*Writer, error) {
+	format, err := loader.FormatOf(config.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case loader.NTriples, loader.JSONLD, loader.Snapshot, loader.Dictionary:
+	default:
+		return nil, fmt.Errorf("writing %s is not supported", format)
+	}
+
+	if config.Sharding == Triples && config.Budget <= 0 {
+		return nil, fmt.Errorf("triple budget of shard is required")
+	}
+
+	// identifiers continue across shards
+	var dict *dictionary.Dictionary
+	if format == loader.Dictionary {
+		h := config.Header
+		if dict, err = dictionary.New(h.Seed, h.From, h.MaxUniversityID); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Writer{
+		config:     config,
+		format:     format,
+		university: config.Header.From,
+		dict:       dict,
+	}, nil
+}
+
+// Format of the output
+func (w *Writer) Format() loader.Format { return w.format }
+
+// Bytes written so far to all files, it is safe to call concurrently
+// with Write.
+func (w *Writer) Bytes() int64 { return w.bytes.Load() }
+
+// Files written so far, the file is listed once it is closed
+func (w *Writer) Files() []lubm.File { return w.files }
+
+// Write bag of knowledge statements
+func (w *Writer) Write(bag spock.Bag) error {
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+
+	if err := w.file.Encode(bag); err != nil {
+		return err
+	}
+
+	if w.config.Sharding == Triples && w.file.triples >= w.config.Budget {
+		return w.close()
+	}
+
+	return nil
+}
+
+// Done marks university as completely written
+func (w *Writer) Done(university int) error {
+	w.university = university + 1
+
+	if w.config.Sharding == University && w.file != nil {
+		return w.close()
+	}
+
+	return nil
+}
+
+// Close the writer
+func (w *Writer) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	return w.close()
+}
+
+func (w *Writer) open() error {
+	path, header := w.config.Path, w.config.Header
+
+	switch w.config.Sharding {
+	case University:
+		path = ShardPath(path, w.university)
+		header.From, header.To = w.university, w.university+1
+	case Triples:
+		path = ShardPath(path, w.shard)
+	}
+
+	file, err := newEncoder(path, w.format, header, w.dict, &w.bytes)
+	if err != nil {
+		return err
+	}
+
+	w.file = file
+	w.shard++
+	return nil
+}
+
+func (w *Writer) close() error {
+	file := w.file
+	w.file = nil
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	w.files = append(w.files, file.File())
+	return nil
+}
+
+// ShardPath returns path of the shard
+func ShardPath(path string, shard int) string {
+	base, gz := path, ""
+	if strings.HasSuffix(base, ".gz") {
+		base, gz = strings.TrimSuffix(base, ".gz"), ".gz"
+	}
+
+	ext := filepath.Ext(base)
+	return fmt.Sprintf("%s.%05d%s%s", strings.TrimSuffix(base, ext), shard, ext, gz)
+}
+
+// encoder of dataset file
+type encoder struct {
+	path    string
+	format  loader.Format
+	file    *hashingFile
+	fd      io.WriteCloser
+	codec   interface{ Encode(spock.Bag) error }
+	flush   func() error
+	triples int
+}
+
+func newEncoder(path string, format loader.Format, header snapshot.Header, dict *dictionary.Dictionary, bytes *atomic.Int64) (*encoder, error) {
+	file, fd, err := create(path, bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	enc := &encoder{path: path, format: format, file: file, fd: fd}
+
+	switch format {
+	case loader.NTriples:
+		codec := ntriples.NewEncoder(fd)
+		enc.codec, enc.flush = codec, codec.Flush
+	case loader.JSONLD:
+		codec := jsonld.NewEncoder(fd)
+		enc.codec, enc.flush = codec, codec.Flush
+	case loader.Snapshot:
+		codec, err := snapshot.NewEncoder(fd, header)
+		if err != nil {
+			fd.Close()
+			return nil, err
+		}
+		enc.codec, enc.flush = codec, codec.Close
+	case loader.Dictionary:
+		codec := dictionary.NewEncoder(fd, dict)
+		enc.codec, enc.flush = codec, codec.Flush
+	}
+
+	return enc, nil
+}
+
+func (enc *encoder) Encode(bag spock.Bag) error {
+	enc.triples += len(bag)
+	return enc.codec.Encode(bag)
+}
+
+func (enc *encoder) Close() error {
+	if err := enc.flush(); err != nil {
+		enc.fd.Close()
+		return err
+	}
+	return enc.fd.Close()
+}
+
+// File describes the encoded file, it is valid after Close. The path is
+// relative to the directory of the manifest.
+func (enc *encoder) File() lubm.File {
+	return lubm.File{
+		Path:    filepath.Base(enc.path),
+		Format:  enc.format.String(),
+		Triples: enc.triples,
+		Bytes:   enc.file.size,
+		SHA256:  hex.EncodeToString(enc.file.hash.Sum(nil)),
+	}
+}
+
+// creates file, the content is compressed if name ends with .gz
+func create(path string, bytes *atomic.Int64) (*hashingFile, io.WriteCloser, error) {
+	fd, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file := &hashingFile{File: fd, hash: sha256.New(), written: bytes}
+	if !strings.HasSuffix(path, ".gz") {
+		return file, file, nil
+	}
+
+	return file, &gzipFile{Writer: gzip.NewWriter(file), file: file}, nil
+}
+
+// hashingFile counts and hashes bytes written to the file
+type hashingFile struct {
+	*os.File
+	hash    hash.Hash
+	size    int64
+	written *atomic.Int64
+}
+
+// Close syncs the file, it is durable once closed
+func (f *hashingFile) Close() error {
+	if err := f.File.Sync(); err != nil {
+		f.File.Close()
+		return err
+	}
+	return f.File.Close()
+}
+
+func (f *hashingFile) Write(p []byte) (int, error) {
+	n, err := f.File.Write(p)
+	f.hash.Write(p[:n])
+	f.size += int64(n)
+	f.written.Add(int64(n))
+	return n, err
+}
+
+type gzipFile struct {
+	*gzip.Writer
+	file *hashingFile
+}
+
+func (f *gzipFile) Close() error {
+	if err := f.Writer.Close(); err != nil {
+		f.file.Close()
+		return err
+	}
+	return f.file.Close()
+}
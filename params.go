@@ -0,0 +1,133 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package lubm
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/kshard/spock/store/ephemeral"
+	"github.com/kshard/xsd"
+)
+
+// Params generates parameters of queries from the dataset (similar to BSBM).
+// Parameters are type-correct and picked deterministically from the seed,
+// each kind of parameter is drawn without replacement until candidates
+// are exhausted, so that consequent queries hit distinct entries.
+type Params struct {
+	graduateCourses *candidates
+	authors         *candidates
+	departments     *candidates
+	teachers        *candidates
+	universities    *candidates
+}
+
+type candidates struct {
+	rand *rand.Rand
+	seq  []string
+	pos  int
+}
+
+// NewParams builds parameters generator from the dataset loaded into store
+func NewParams(seed int64, store *ephemeral.Store) (*Params, error) {
+	rnd := rand.New(rand.NewSource(seed))
+	params := &Params{}
+
+	for _, x := range []struct {
+		ref   **candidates
+		query string
+	}{
+		{&params.graduateCourses, `q(x) :- f(x, rdf:type, ub:GraduateCourse).`},
+		{&params.authors, `q(x) :- f(p, ub:publicationAuthor, x).`},
+		{&params.departments, `q(x) :- f(x, rdf:type, ub:Department).`},
+		{&params.teachers, `q(x) :- f(x, ub:teacherOf, c).`},
+		{&params.universities, `q(x) :- f(x, rdf:type, ub:University).`},
+	} {
+		c, err := newCandidates(rnd, store, x.query)
+		if err != nil {
+			return nil, err
+		}
+		*x.ref = c
+	}
+
+	return params, nil
+}
+
+func newCandidates(rnd *rand.Rand, store *ephemeral.Store, query string) (*candidates, error) {
+	result, err := Eval(store, "f(s, p, o).\n"+query)
+	if err != nil {
+		return nil, err
+	}
+
+	set := map[string]struct{}{}
+	for _, row := range result.Rows {
+		if iri, ok := row[0].(xsd.AnyURI); ok {
+			set[iri.String()] = struct{}{}
+		}
+	}
+
+	if len(set) == 0 {
+		return nil, fmt.Errorf("dataset has no candidates for %s", query)
+	}
+
+	// order of the store is not defined, candidates are sorted before shuffle
+	seq := make([]string, 0, len(set))
+	for iri := range set {
+		seq = append(seq, iri)
+	}
+	sort.Strings(seq)
+
+	return &candidates{rand: rnd, seq: seq}, nil
+}
+
+func (c *candidates) next() string {
+	if c.pos == 0 {
+		c.rand.Shuffle(len(c.seq), func(i, j int) { c.seq[i], c.seq[j] = c.seq[j], c.seq[i] })
+	}
+
+	iri := c.seq[c.pos]
+	c.pos = (c.pos + 1) % len(c.seq)
+	return iri
+}
+
+// GraduateCourse returns parameter of Query1
+func (params *Params) GraduateCourse() string { return params.graduateCourses.next() }
+
+// Author returns parameter of Query3
+func (params *Params) Author() string { return params.authors.next() }
+
+// Department returns parameter of Query4 and Query5
+func (params *Params) Department() string { return params.departments.next() }
+
+// Teacher returns parameter of Query7
+func (params *Params) Teacher() string { return params.teachers.next() }
+
+// University returns parameter of Query8
+func (params *Params) University() string { return params.universities.next() }
+
+// Query returns query #id (starting from 1) instantiated with next parameters
+func (params *Params) Query(id int) (string, error) {
+	switch id {
+	case 1:
+		return Query1(params.GraduateCourse()), nil
+	case 3:
+		return Query3(params.Author()), nil
+	case 4:
+		return Query4(params.Department()), nil
+	case 5:
+		return Query5(params.Department()), nil
+	case 7:
+		return Query7(params.Teacher()), nil
+	case 8:
+		return Query8(params.University()), nil
+	default:
+		return Query(id)
+	}
+}
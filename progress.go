@@ -0,0 +1,95 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package lubm
+
+import (
+	"math"
+	"time"
+)
+
+// Progress of the dataset generation
+type Progress struct {
+	University   int // university being generated
+	Universities int // universities generated so far
+	Departments  int // departments generated so far
+	Total        int // universities to generate
+	Triples      int
+	Elapsed      time.Duration
+	ETA          time.Duration // 0 if unknown or completed
+}
+
+// Rate of generation, triples per second
+func (p Progress) Rate() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Triples) / p.Elapsed.Seconds()
+}
+
+// Interval between progress reports
+const progressInterval = time.Second
+
+// tracks progress of the generation
+type tracker struct {
+	Progress
+	callback func(Progress)
+	started  time.Time
+	reported time.Time
+	current  int // departments of the current university
+}
+
+func (t *tracker) university(id int) {
+	if t.started.IsZero() {
+		t.started = time.Now()
+	}
+	t.University = id
+	t.current = 0
+}
+
+func (t *tracker) department() {
+	t.Departments++
+	t.current++
+	if time.Since(t.reported) >= progressInterval {
+		t.report()
+	}
+}
+
+func (t *tracker) done() {
+	t.Universities++
+	t.current = 0
+	t.report()
+}
+
+func (t *tracker) report() {
+	if t.callback == nil {
+		return
+	}
+
+	t.reported = time.Now()
+	t.Elapsed = t.reported.Sub(t.started)
+	t.ETA = t.eta()
+	t.callback(t.Progress)
+}
+
+// the incomplete university is accounted by expected number of departments
+func (t *tracker) eta() time.Duration {
+	if t.Universities >= t.Total {
+		return 0
+	}
+
+	done := float64(t.Universities)
+	if t.current > 0 {
+		done += math.Min(float64(t.current)/loop(15, 11), 0.99)
+	}
+	if done == 0 {
+		return 0
+	}
+
+	return time.Duration(float64(t.Elapsed) * (float64(t.Total) - done) / done)
+}
@@ -10,6 +10,14 @@ package lubm
 
 import "fmt"
 
+// default parameters of queries
+const (
+	defaultUniversity = "edu:University0"
+	defaultDepartment = "edu:University0.Department0"
+	defaultCourse     = "edu:University0.Department0/GraduateCourse5"
+	defaultFaculty    = "edu:University0.Department0/AssistantProfessor0"
+)
+
 //
 // See http://swat.cse.lehigh.edu/projects/lubm/queries-sparql.txt
 // See http://swat.cse.lehigh.edu/projects/lubm/lubm.jpg
@@ -24,7 +32,7 @@ import "fmt"
 //	 ?X ub:takesCourse http://www.Department0.University0.edu/GraduateCourse0
 //	}
 func Query1(course ...string) string {
-	c := "edu:University0.Department0/GraduateCourse5"
+	c := defaultCourse
 	if len(course) != 0 {
 		c = course[0]
 	}
@@ -74,7 +82,7 @@ func Query2() string {
 //	  ?X ub:publicationAuthor http://www.Department0.University0.edu/AssistantProfessor0
 //	}
 func Query3(author ...string) string {
-	a := "edu:University0.Department0/AssistantProfessor0"
+	a := defaultFaculty
 	if len(author) != 0 {
 		a = author[0]
 	}
@@ -101,7 +109,7 @@ func Query3(author ...string) string {
 //	  ?X ub:telephone ?Y3
 //	}
 func Query4(dept ...string) string {
-	d := "edu:University0.Department0"
+	d := defaultDepartment
 	if len(dept) != 0 {
 		d = dept[0]
 	}
@@ -127,7 +135,7 @@ func Query4(dept ...string) string {
 //	  ?X ub:memberOf <http://www.Department0.University0.edu>
 //	}
 func Query5(dept ...string) string {
-	d := "edu:University0.Department0"
+	d := defaultDepartment
 	if len(dept) != 0 {
 		d = dept[0]
 	}
@@ -168,7 +176,7 @@ func Query6() string {
 //		<http://www.Department0.University0.edu/AssociateProfessor0> ub:teacherOf, ?Y
 //	}
 func Query7(teacher ...string) string {
-	t := "edu:University0.Department0/AssistantProfessor0"
+	t := defaultFaculty
 	if len(teacher) != 0 {
 		t = teacher[0]
 	}
@@ -196,7 +204,7 @@ func Query7(teacher ...string) string {
 //	  ?X ub:emailAddress ?Z
 //	}
 func Query8(university ...string) string {
-	u := "edu:University0"
+	u := defaultUniversity
 	if len(university) != 0 {
 		u = university[0]
 	}
@@ -236,3 +244,21 @@ func Query9() string {
 			f(x, ub:takesCourse, z).
 	`
 }
+
+// Queries returns lubm.Query1 ... lubm.Query9 with default parameters,
+// query #id is at index id - 1
+func Queries() []string {
+	return []string{
+		Query1(), Query2(), Query3(), Query4(), Query5(),
+		Query6(), Query7(), Query8(), Query9(),
+	}
+}
+
+// Query returns query #id (starting from 1) with default parameters
+func Query(id int) (string, error) {
+	seq := Queries()
+	if id < 1 || id > len(seq) {
+		return "", fmt.Errorf("unknown query #%d", id)
+	}
+	return seq[id-1], nil
+}
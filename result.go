@@ -0,0 +1,234 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package lubm
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/lubm/internal/adapter"
+	"github.com/kshard/sigma"
+	"github.com/kshard/sigma/asm"
+	"github.com/kshard/sigma/ast"
+	"github.com/kshard/sigma/lang"
+	"github.com/kshard/sigma/vm"
+	"github.com/kshard/spock/store/ephemeral"
+	"github.com/kshard/xsd"
+)
+
+// Goal of the benchmark queries
+const Goal = "q"
+
+// Result of query evaluation, tuples bound to variables of the goal q(...)
+type Result struct {
+	Vars []string
+	Rows [][]xsd.Value
+}
+
+// Len returns number of tuples in the result
+func (result *Result) Len() int { return len(result.Rows) }
+
+// Eval evaluates the query over the store and returns bound tuples
+func Eval(store *ephemeral.Store, query string) (*Result, error) {
+	return EvalContext(context.Background(), store, query)
+}
+
+// EvalContext evaluates the query over the store, the evaluation is
+// aborted with context error once context is done.
+func EvalContext(ctx context.Context, store *ephemeral.Store, query string) (*Result, error) {
+	return eval(query, adapter.NewStreamContext(ctx, store))
+}
+
+// evaluates the query over the stream of knowledge statements
+func eval(query string, stream func([]vm.Addr) vm.Stream) (*Result, error) {
+	rules, err := lang.NewParser(bytes.NewBufferString(query)).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	vars, err := goalOf(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	machine, err := sigma.New(Goal, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	link := asm.NewContext().Add("f", stream)
+	reader := sigma.Stream(link, machine)
+
+	result := &Result{Vars: vars, Rows: [][]xsd.Value{}}
+	for {
+		row := make([]xsd.Value, len(vars))
+		if err := reader.Read(row); err != nil {
+			if err == vm.EndOfStream {
+				return result, nil
+			}
+			return nil, err
+		}
+		result.Rows = append(result.Rows, row)
+	}
+}
+
+// variables of the goal
+func goalOf(rules ast.Rules) ([]string, error) {
+	for _, rule := range rules {
+		if horn, ok := rule.(*ast.Horn); ok && horn.Head.Name == Goal {
+			vars := make([]string, len(horn.Head.Terms))
+			for i, term := range horn.Head.Terms {
+				vars[i] = term.Name
+			}
+			return vars, nil
+		}
+	}
+
+	return nil, fmt.Errorf("goal %s(...) is not defined", Goal)
+}
+
+//
+// See https://www.w3.org/TR/sparql11-results-json/
+//
+
+type jsonResults struct {
+	Head    jsonHead    `json:"head"`
+	Results jsonBinding `json:"results"`
+}
+
+type jsonHead struct {
+	Vars []string `json:"vars"`
+}
+
+type jsonBinding struct {
+	Bindings []map[string]jsonTerm `json:"bindings"`
+}
+
+type jsonTerm struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// WriteJSON writes result using SPARQL 1.1 Query Results JSON Format
+func (result *Result) WriteJSON(w io.Writer) error {
+	doc := jsonResults{
+		Head:    jsonHead{Vars: result.Vars},
+		Results: jsonBinding{Bindings: make([]map[string]jsonTerm, len(result.Rows))},
+	}
+
+	for i, row := range result.Rows {
+		binding := map[string]jsonTerm{}
+		for j, val := range row {
+			switch v := val.(type) {
+			case xsd.AnyURI:
+				binding[result.Vars[j]] = jsonTerm{Type: "uri", Value: uriOf(v)}
+			case nil:
+				// unbound variable is omitted
+			default:
+				binding[result.Vars[j]] = jsonTerm{Type: "literal", Value: literalOf(v)}
+			}
+		}
+		doc.Results.Bindings[i] = binding
+	}
+
+	return json.NewEncoder(w).Encode(doc)
+}
+
+//
+// See https://www.w3.org/TR/sparql11-results-csv-tsv/
+//
+
+// WriteCSV writes result using SPARQL 1.1 Query Results CSV Format
+func (result *Result) WriteCSV(w io.Writer) error {
+	codec := csv.NewWriter(w)
+	codec.UseCRLF = true
+
+	if err := codec.Write(result.Vars); err != nil {
+		return err
+	}
+
+	seq := make([]string, len(result.Vars))
+	for _, row := range result.Rows {
+		for i, val := range row {
+			switch v := val.(type) {
+			case xsd.AnyURI:
+				seq[i] = uriOf(v)
+			case nil:
+				seq[i] = ""
+			default:
+				seq[i] = literalOf(v)
+			}
+		}
+
+		if err := codec.Write(seq); err != nil {
+			return err
+		}
+	}
+
+	codec.Flush()
+	return codec.Error()
+}
+
+var tsvEscape = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\t", `\t`,
+	"\n", `\n`,
+	"\r", `\r`,
+)
+
+// WriteTSV writes result using SPARQL 1.1 Query Results TSV Format
+func (result *Result) WriteTSV(w io.Writer) error {
+	seq := make([]string, len(result.Vars))
+	for i, name := range result.Vars {
+		seq[i] = "?" + name
+	}
+
+	if _, err := io.WriteString(w, strings.Join(seq, "\t")+"\n"); err != nil {
+		return err
+	}
+
+	for _, row := range result.Rows {
+		for i, val := range row {
+			switch v := val.(type) {
+			case xsd.AnyURI:
+				seq[i] = "<" + uriOf(v) + ">"
+			case nil:
+				seq[i] = ""
+			default:
+				seq[i] = `"` + tsvEscape.Replace(literalOf(v)) + `"`
+			}
+		}
+
+		if _, err := io.WriteString(w, strings.Join(seq, "\t")+"\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func uriOf(v xsd.AnyURI) string {
+	return ToURI(curie.IRI(v.String()))
+}
+
+func literalOf(v xsd.Value) string {
+	switch s := v.(type) {
+	case xsd.String:
+		return string(s)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,114 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package sparql
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrInference is returned for queries whose answer depends on the
+// hierarchy of univ-bench ontology. The generator materializes only the
+// most specific classes and properties, sigma rules have no inference,
+// such queries would silently return incomplete or empty result.
+var ErrInference = errors.New("query requires inference")
+
+// classes and properties of univ-bench ontology that are not materialized
+// by the generator, the value explains how they are implied.
+var implied = map[string]string{
+	"ub:Person":            "superclass of Student, Faculty and Employee",
+	"ub:Employee":          "superclass of Faculty",
+	"ub:Faculty":           "superclass of Professor and Lecturer",
+	"ub:Professor":         "superclass of FullProfessor, AssociateProfessor and AssistantProfessor",
+	"ub:Student":           "superclass of UndergraduateStudent and GraduateStudent",
+	"ub:Course":            "superclass of GraduateCourse",
+	"ub:Organization":      "superclass of University, Department and ResearchGroup",
+	"ub:Work":              "superclass of Course and Publication",
+	"ub:Chair":             "defined as Professor heading Department",
+	"ub:Dean":              "defined as Professor heading College",
+	"ub:TeachingAssistant": "defined as Person assisting Course",
+	"ub:ResearchAssistant": "defined as Student working for ResearchGroup",
+	"ub:degreeFrom":        "superproperty of undergraduateDegreeFrom, mastersDegreeFrom and doctoralDegreeFrom",
+	"ub:hasAlumnus":        "inverse of degreeFrom",
+	"ub:member":            "inverse of memberOf",
+}
+
+// students are members of departments, faculties only work for them
+var students = []string{"ub:UndergraduateStudent", "ub:GraduateStudent"}
+
+// Inference returns ErrInference if the query is not answered by the
+// generated data as it is.
+func (q *Query) Inference() error {
+	types := map[string][]string{}
+	for _, p := range q.Patterns {
+		if p.P.Value == "rdf:type" && p.O.Kind == IRI {
+			types[p.S.String()] = append(types[p.S.String()], p.O.Value)
+		}
+	}
+
+	is := func(t Term, classes ...string) bool {
+		for _, c := range types[t.String()] {
+			for _, class := range classes {
+				if c == class {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	reasons := map[string]string{}
+	for _, p := range q.Patterns {
+		if p.P.Kind == IRI && p.P.Value == "rdf:type" {
+			if why, has := implied[p.O.Value]; has {
+				reasons[p.O.Value] = why
+			}
+			continue
+		}
+
+		if why, has := implied[p.P.Value]; has {
+			reasons[p.P.Value] = why
+		}
+
+		switch p.P.Value {
+		case "ub:memberOf":
+			// worksFor and headOf are subproperties of memberOf
+			if !is(p.S, students...) {
+				reasons[p.P.Value] = "superproperty of worksFor, unless subject is student"
+			}
+		case "ub:subOrganizationOf":
+			// research group is sub-organization of department only
+			if !is(p.S, "ub:Department") && !is(p.O, "ub:Department") && !isDepartment(p.O) {
+				reasons[p.P.Value] = "transitive, unless subject or object is department"
+			}
+		}
+	}
+
+	if len(reasons) == 0 {
+		return nil
+	}
+
+	seq := make([]string, 0, len(reasons))
+	for term, why := range reasons {
+		seq = append(seq, fmt.Sprintf("%s is %s", term, why))
+	}
+	sort.Strings(seq)
+
+	return fmt.Errorf("%w: %s", ErrInference, strings.Join(seq, "; "))
+}
+
+// edu:University0.Department0 is department
+func isDepartment(t Term) bool {
+	return t.Kind == IRI &&
+		strings.HasPrefix(t.Value, "edu:") &&
+		strings.Contains(t.Value, ".") &&
+		!strings.Contains(t.Value, "/")
+}
@@ -0,0 +1,213 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package sparql
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Kind of lexical token
+type kind int
+
+const (
+	tEOF     kind = iota
+	tIRI          // <http://...> or bare http://...
+	tPName        // prefix:local
+	tVar          // ?x or $x
+	tString       // "..." or '...'
+	tKeyword      // PREFIX, SELECT, WHERE, ...
+	tSymbol       // { } . ; , *
+)
+
+type token struct {
+	kind    kind
+	literal string
+}
+
+func (t token) String() string {
+	if t.kind == tEOF {
+		return "end of query"
+	}
+	return fmt.Sprintf("`%s`", t.literal)
+}
+
+// lexer of SPARQL subset used by the benchmark
+type lexer struct {
+	in  []rune
+	pos int
+}
+
+func newLexer(query string) *lexer {
+	return &lexer{in: []rune(query)}
+}
+
+func (lx *lexer) peek() rune {
+	if lx.pos >= len(lx.in) {
+		return 0
+	}
+	return lx.in[lx.pos]
+}
+
+func (lx *lexer) skip() {
+	for lx.pos < len(lx.in) {
+		ch := lx.in[lx.pos]
+		switch {
+		case unicode.IsSpace(ch):
+			lx.pos++
+		case ch == '#':
+			for lx.pos < len(lx.in) && lx.in[lx.pos] != '\n' {
+				lx.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (lx *lexer) scan() (token, error) {
+	lx.skip()
+
+	ch := lx.peek()
+	switch {
+	case ch == 0:
+		return token{kind: tEOF}, nil
+
+	case ch == '<':
+		return lx.scanIRI()
+
+	case ch == '?' || ch == '$':
+		lx.pos++
+		name := lx.scanWhile(isNameChar)
+		if name == "" {
+			return token{}, fmt.Errorf("syntax error: variable name is expected at %d", lx.pos)
+		}
+		return token{kind: tVar, literal: name}, nil
+
+	case ch == '"' || ch == '\'':
+		return lx.scanString(ch)
+
+	case strings.ContainsRune("{}.;,*", ch):
+		lx.pos++
+		return token{kind: tSymbol, literal: string(ch)}, nil
+
+	case isNameChar(ch) || ch == ':':
+		word := lx.scanWhile(func(ch rune) bool { return isNameChar(ch) || ch == ':' || ch == '.' })
+
+		// Official LUBM queries contain bare IRIs (http://...)
+		if strings.HasPrefix(word, "http:") || strings.HasPrefix(word, "https:") {
+			word += lx.scanWhile(func(ch rune) bool { return !unicode.IsSpace(ch) && !strings.ContainsRune("{}<>,;", ch) })
+			return token{kind: tIRI, literal: word}, nil
+		}
+
+		// the dot terminates the triple pattern
+		for strings.HasSuffix(word, ".") {
+			word = word[:len(word)-1]
+			lx.pos--
+		}
+
+		if strings.ContainsRune(word, ':') {
+			return token{kind: tPName, literal: word}, nil
+		}
+		return token{kind: tKeyword, literal: word}, nil
+
+	default:
+		return token{}, fmt.Errorf("syntax error: unexpected `%c` at %d", ch, lx.pos)
+	}
+}
+
+func (lx *lexer) scanWhile(f func(rune) bool) string {
+	start := lx.pos
+	for lx.pos < len(lx.in) && f(lx.in[lx.pos]) {
+		lx.pos++
+	}
+	return string(lx.in[start:lx.pos])
+}
+
+func (lx *lexer) scanIRI() (token, error) {
+	start := lx.pos
+	lx.pos++
+	iri := lx.scanWhile(func(ch rune) bool { return ch != '>' && !unicode.IsSpace(ch) })
+	if lx.peek() != '>' {
+		return token{}, fmt.Errorf("syntax error: unterminated IRI at %d", start)
+	}
+	lx.pos++
+
+	return token{kind: tIRI, literal: iri}, nil
+}
+
+func (lx *lexer) scanString(quote rune) (token, error) {
+	start := lx.pos
+	lx.pos++
+
+	var buf strings.Builder
+	for {
+		if lx.pos >= len(lx.in) {
+			return token{}, fmt.Errorf("syntax error: unterminated string at %d", start)
+		}
+
+		ch := lx.in[lx.pos]
+		lx.pos++
+		switch ch {
+		case quote:
+			if err := lx.scanDataType(); err != nil {
+				return token{}, err
+			}
+			return token{kind: tString, literal: buf.String()}, nil
+		case '\\':
+			if lx.pos >= len(lx.in) {
+				return token{}, fmt.Errorf("syntax error: unterminated string at %d", start)
+			}
+			esc := lx.in[lx.pos]
+			lx.pos++
+			switch esc {
+			case 't':
+				buf.WriteRune('\t')
+			case 'n':
+				buf.WriteRune('\n')
+			case 'r':
+				buf.WriteRune('\r')
+			default:
+				buf.WriteRune(esc)
+			}
+		default:
+			buf.WriteRune(ch)
+		}
+	}
+}
+
+// The dataset contains only xsd:string literals
+func (lx *lexer) scanDataType() error {
+	switch lx.peek() {
+	case '@':
+		return fmt.Errorf("syntax error: language tags are not supported at %d", lx.pos)
+	case '^':
+		start := lx.pos
+		lx.pos++
+		if lx.peek() != '^' {
+			return fmt.Errorf("syntax error: unexpected `^` at %d", start)
+		}
+		lx.pos++
+
+		t, err := lx.scan()
+		if err != nil {
+			return err
+		}
+		if t.literal != "xsd:string" && t.literal != "http://www.w3.org/2001/XMLSchema#string" {
+			return fmt.Errorf("syntax error: data type %s is not supported at %d", t, start)
+		}
+	}
+
+	return nil
+}
+
+func isNameChar(ch rune) bool {
+	return unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch == '_' || ch == '-'
+}
@@ -0,0 +1,85 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package sparql
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// See http://swat.cse.lehigh.edu/projects/lubm/queries-sparql.txt
+//
+//go:embed queries-sparql.txt
+var official []byte
+
+// Official returns 14 queries defined by the benchmark. Most of them assume
+// the class hierarchy of univ-bench ontology, Query.Inference tells which
+// one cannot be evaluated over the generated data, these queries have to
+// be skipped. Only Query1, Query2, Query3 and Query14 are answered as they
+// are, Query1 asks for GraduateCourse0 that might have no students.
+func Official() ([]*Query, error) {
+	return Load(bytes.NewReader(official))
+}
+
+var heading = regexp.MustCompile(`^#\s*(Query\d+)\s*$`)
+
+// Load queries from the file formatted as queries-sparql.txt, each query
+// is preceded by heading comment `# QueryN`.
+func Load(r io.Reader) ([]*Query, error) {
+	queries := []*Query{}
+
+	name := ""
+	text := strings.Builder{}
+	flush := func() error {
+		if strings.TrimSpace(text.String()) == "" {
+			return nil
+		}
+
+		q, err := Parse(text.String())
+		if err != nil {
+			if name != "" {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			return err
+		}
+		q.Name = name
+		queries = append(queries, q)
+		text.Reset()
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if seq := heading.FindStringSubmatch(line); seq != nil {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			name = seq[1]
+			continue
+		}
+
+		text.WriteString(line)
+		text.WriteString("\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return queries, nil
+}
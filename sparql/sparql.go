@@ -0,0 +1,524 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+// Package sparql translates SPARQL SELECT queries with basic graph pattern
+// into sigma rules evaluated by the benchmark.
+package sparql
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/lubm"
+)
+
+// Kind of the term
+type Kind int
+
+const (
+	Var Kind = iota
+	IRI
+	Literal
+)
+
+// Term of triple pattern
+type Term struct {
+	Kind  Kind
+	Value string
+}
+
+func (t Term) String() string {
+	switch t.Kind {
+	case Var:
+		return t.Value
+	case IRI:
+		if simpleCURIE.MatchString(t.Value) {
+			return t.Value
+		}
+		return "<" + t.Value + ">"
+	default:
+		return `"` + t.Value + `"`
+	}
+}
+
+// Triple pattern
+type Pattern struct {
+	S, P, O Term
+}
+
+func (p Pattern) String() string {
+	return fmt.Sprintf("f(%s, %s, %s)", p.S, p.P, p.O)
+}
+
+// Query is SELECT query with basic graph pattern
+type Query struct {
+	Name     string
+	Vars     []string
+	Patterns []Pattern
+}
+
+var (
+	// CURIE that is expressible as sigma atom
+	simpleCURIE = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*:[a-zA-Z][a-zA-Z0-9_]*$`)
+	// variable that is expressible as sigma atom
+	simpleVar = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+	// names reserved by sigma parser for constants
+	reservedVar = regexp.MustCompile(`^x[usnf][0-9]+$`)
+)
+
+// Translate SPARQL query into sigma rules, queries requiring inference
+// are rejected with ErrInference.
+func Translate(query string) (string, error) {
+	q, err := Parse(query)
+	if err != nil {
+		return "", err
+	}
+
+	if err := q.Inference(); err != nil {
+		return "", err
+	}
+
+	return q.Sigma(), nil
+}
+
+// Sigma returns sigma rules equivalent to the query. The triple patterns are
+// ordered so that each pattern binds as many terms as possible by constants
+// or variables bound by preceding patterns.
+func (q *Query) Sigma() string {
+	var buf strings.Builder
+
+	buf.WriteString("f(s, p, o).\n\n")
+	buf.WriteString(lubm.Goal + "(" + strings.Join(q.Vars, ", ") + ") :-\n")
+
+	for i, pattern := range q.plan() {
+		buf.WriteString("\t" + pattern.String())
+		if i != len(q.Patterns)-1 {
+			buf.WriteString(",\n")
+		}
+	}
+	buf.WriteString(".\n")
+
+	return buf.String()
+}
+
+// greedy join ordering
+func (q *Query) plan() []Pattern {
+	bound := map[string]bool{}
+	isBound := func(t Term) bool { return t.Kind != Var || bound[t.Value] }
+
+	// rdf:type is less selective than other constant
+	weight := func(p Pattern) int {
+		w := 0
+		if isBound(p.S) {
+			w += 4
+		}
+		if isBound(p.P) {
+			w += 1
+		}
+		if isBound(p.O) {
+			if p.O.Kind == IRI && p.P.Value == "rdf:type" {
+				w += 1
+			} else {
+				w += 2
+			}
+		}
+		return w
+	}
+
+	seq := make([]Pattern, 0, len(q.Patterns))
+	used := make([]bool, len(q.Patterns))
+	for range q.Patterns {
+		best := -1
+		for i, p := range q.Patterns {
+			if !used[i] && (best == -1 || weight(p) > weight(q.Patterns[best])) {
+				best = i
+			}
+		}
+
+		used[best] = true
+		p := q.Patterns[best]
+		for _, t := range []Term{p.S, p.P, p.O} {
+			if t.Kind == Var {
+				bound[t.Value] = true
+			}
+		}
+		seq = append(seq, p)
+	}
+
+	return seq
+}
+
+//------------------------------------------------------------------------------
+//
+// Parser
+//
+//------------------------------------------------------------------------------
+
+type parser struct {
+	lexer    *lexer
+	head     *token
+	prefixes map[string]string
+}
+
+// Parse SPARQL SELECT query with basic graph pattern. Prefixed names and
+// full IRIs are mapped onto CURIE scheme used by the dataset.
+//
+// The parser tolerates quirks of the official LUBM queries: bare IRIs,
+// comma separated projection and commas between terms of triple.
+func Parse(query string) (*Query, error) {
+	p := &parser{
+		lexer:    newLexer(query),
+		prefixes: map[string]string{},
+	}
+
+	q, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+func (p *parser) next() (token, error) {
+	if p.head != nil {
+		t := *p.head
+		p.head = nil
+		return t, nil
+	}
+
+	return p.lexer.scan()
+}
+
+func (p *parser) peek() (token, error) {
+	t, err := p.next()
+	if err != nil {
+		return t, err
+	}
+	p.head = &t
+	return t, nil
+}
+
+func (p *parser) expect(kind kind, literal string) error {
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+
+	if t.kind != kind || !strings.EqualFold(t.literal, literal) {
+		return fmt.Errorf("syntax error: expected `%s`, got %s", literal, t)
+	}
+
+	return nil
+}
+
+func (p *parser) isNext(kind kind, literal string) (bool, error) {
+	t, err := p.peek()
+	if err != nil {
+		return false, err
+	}
+
+	return t.kind == kind && strings.EqualFold(t.literal, literal), nil
+}
+
+// skips optional symbol
+func (p *parser) optional(literal string) error {
+	has, err := p.isNext(tSymbol, literal)
+	if err != nil || !has {
+		return err
+	}
+
+	_, err = p.next()
+	return err
+}
+
+// PREFIX* SELECT Vars WHERE? { Triples }
+func (p *parser) parseQuery() (*Query, error) {
+	for {
+		has, err := p.isNext(tKeyword, "PREFIX")
+		if err != nil {
+			return nil, err
+		}
+		if !has {
+			break
+		}
+		if err := p.parsePrefix(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.expect(tKeyword, "SELECT"); err != nil {
+		return nil, err
+	}
+
+	vars, err := p.parseVars()
+	if err != nil {
+		return nil, err
+	}
+
+	has, err := p.isNext(tKeyword, "WHERE")
+	if err != nil {
+		return nil, err
+	}
+	if has {
+		p.next()
+	}
+
+	patterns, err := p.parseGroup()
+	if err != nil {
+		return nil, err
+	}
+
+	if t, err := p.next(); err != nil || t.kind != tEOF {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("syntax error: only basic graph pattern is supported, got %s", t)
+	}
+
+	if vars == nil {
+		vars = varsOf(patterns)
+	}
+
+	return &Query{Vars: vars, Patterns: patterns}, nil
+}
+
+// PREFIX pname: <iri>
+func (p *parser) parsePrefix() error {
+	p.next()
+
+	name, err := p.next()
+	if err != nil {
+		return err
+	}
+	if name.kind != tPName || !strings.HasSuffix(name.literal, ":") {
+		return fmt.Errorf("syntax error: expected prefix name, got %s", name)
+	}
+
+	iri, err := p.next()
+	if err != nil {
+		return err
+	}
+	if iri.kind != tIRI {
+		return fmt.Errorf("syntax error: expected IRI, got %s", iri)
+	}
+
+	p.prefixes[strings.TrimSuffix(name.literal, ":")] = iri.literal
+	return nil
+}
+
+// ?x ?y ... or *, returns nil for *
+func (p *parser) parseVars() ([]string, error) {
+	if has, err := p.isNext(tSymbol, "*"); err != nil || has {
+		p.next()
+		return nil, err
+	}
+
+	if has, err := p.isNext(tKeyword, "DISTINCT"); err != nil || has {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("syntax error: DISTINCT is not supported")
+	}
+
+	vars := []string{}
+	for {
+		t, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case t.kind == tVar:
+			vars = append(vars, t.literal)
+			p.next()
+		case t.kind == tSymbol && t.literal == ",":
+			p.next()
+		default:
+			if len(vars) == 0 {
+				return nil, fmt.Errorf("syntax error: expected variable, got %s", t)
+			}
+			return vars, nil
+		}
+	}
+}
+
+// { s p o ; p o , o . }
+func (p *parser) parseGroup() ([]Pattern, error) {
+	if err := p.expect(tSymbol, "{"); err != nil {
+		return nil, err
+	}
+
+	patterns := []Pattern{}
+	for {
+		if has, err := p.isNext(tSymbol, "}"); err != nil || has {
+			p.next()
+			return patterns, err
+		}
+
+		s, err := p.parseTerm(false)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.optional(","); err != nil {
+			return nil, err
+		}
+
+		seq, err := p.parsePredicateObjectList(s)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, seq...)
+
+		if err := p.optional("."); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parsePredicateObjectList(s Term) ([]Pattern, error) {
+	patterns := []Pattern{}
+
+	for {
+		v, err := p.parseTerm(true)
+		if err != nil {
+			return nil, err
+		}
+		if v.Kind == Literal {
+			return nil, fmt.Errorf("syntax error: literal %s cannot be predicate", v)
+		}
+		if err := p.optional(","); err != nil {
+			return nil, err
+		}
+
+		for {
+			o, err := p.parseTerm(false)
+			if err != nil {
+				return nil, err
+			}
+			patterns = append(patterns, Pattern{S: s, P: v, O: o})
+
+			if has, err := p.isNext(tSymbol, ","); err != nil || !has {
+				if err != nil {
+					return nil, err
+				}
+				break
+			}
+			p.next()
+		}
+
+		if has, err := p.isNext(tSymbol, ";"); err != nil || !has {
+			return patterns, err
+		}
+		p.next()
+
+		// trailing semicolon
+		if has, err := p.isNext(tSymbol, "."); err != nil || has {
+			return patterns, err
+		}
+		if has, err := p.isNext(tSymbol, "}"); err != nil || has {
+			return patterns, err
+		}
+	}
+}
+
+func (p *parser) parseTerm(isVerb bool) (Term, error) {
+	t, err := p.next()
+	if err != nil {
+		return Term{}, err
+	}
+
+	switch t.kind {
+	case tVar:
+		return Term{Kind: Var, Value: t.literal}, nil
+	case tIRI:
+		return Term{Kind: IRI, Value: string(lubm.FromURI(t.literal))}, nil
+	case tPName:
+		iri, err := p.resolve(t.literal)
+		if err != nil {
+			return Term{}, err
+		}
+		return Term{Kind: IRI, Value: string(iri)}, nil
+	case tString:
+		return Term{Kind: Literal, Value: t.literal}, nil
+	case tKeyword:
+		if isVerb && t.literal == "a" {
+			return Term{Kind: IRI, Value: "rdf:type"}, nil
+		}
+	}
+
+	return Term{}, fmt.Errorf("syntax error: unexpected %s", t)
+}
+
+// resolves prefixed name into CURIE of the dataset
+func (p *parser) resolve(pname string) (curie.IRI, error) {
+	prefix, local := curie.Seq(curie.IRI(pname))
+
+	if ns, has := p.prefixes[prefix]; has {
+		return lubm.FromURI(ns + local), nil
+	}
+
+	if _, has := lubm.Namespaces[prefix]; has || prefix == "edu" {
+		return curie.IRI(pname), nil
+	}
+
+	return "", fmt.Errorf("syntax error: undefined prefix %s", prefix)
+}
+
+func varsOf(patterns []Pattern) []string {
+	seen := map[string]bool{}
+	vars := []string{}
+	for _, p := range patterns {
+		for _, t := range []Term{p.S, p.P, p.O} {
+			if t.Kind == Var && !seen[t.Value] {
+				seen[t.Value] = true
+				vars = append(vars, t.Value)
+			}
+		}
+	}
+	return vars
+}
+
+// checks that query is expressible with sigma
+func (q *Query) validate() error {
+	if len(q.Patterns) == 0 {
+		return fmt.Errorf("query has empty graph pattern")
+	}
+
+	bound := map[string]bool{}
+	for _, v := range varsOf(q.Patterns) {
+		if !simpleVar.MatchString(v) || reservedVar.MatchString(v) {
+			return fmt.Errorf("variable ?%s is not supported", v)
+		}
+		bound[v] = true
+	}
+
+	for _, v := range q.Vars {
+		if !bound[v] {
+			return fmt.Errorf("variable ?%s is not bound by graph pattern", v)
+		}
+	}
+
+	for _, p := range q.Patterns {
+		if p.O.Kind == Literal && strings.ContainsRune(p.O.Value, '"') {
+			return fmt.Errorf("literal %s is not supported", p.O)
+		}
+
+		if p.S.Kind == Var && (p.P.Kind == Var && p.S.Value == p.P.Value || p.O.Kind == Var && p.S.Value == p.O.Value) ||
+			p.P.Kind == Var && p.O.Kind == Var && p.P.Value == p.O.Value {
+			return fmt.Errorf("variable repeated within triple pattern %s is not supported", p)
+		}
+	}
+
+	return nil
+}
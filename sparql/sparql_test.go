@@ -0,0 +1,171 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package sparql_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kshard/lubm"
+	"github.com/kshard/lubm/sparql"
+	"github.com/kshard/spock/store/ephemeral"
+)
+
+func TestTranslate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		query string
+		sigma []string
+	}{
+		"prefix": {
+			query: `
+				PREFIX rdf: <http://www.w3.org/1999/02/22-rdf-syntax-ns#>
+				PREFIX univ: <http://www.lehigh.edu/~zhp2/2004/0401/univ-bench.owl#>
+				SELECT ?x WHERE { ?x rdf:type univ:GraduateStudent }`,
+			sigma: []string{"q(x) :-", "f(x, rdf:type, ub:GraduateStudent)"},
+		},
+		"iri": {
+			query: `SELECT ?x WHERE { ?x ub:worksFor <http://www.Department0.University0.edu> }`,
+			sigma: []string{"f(x, ub:worksFor, <edu:University0.Department0>)"},
+		},
+		"a": {
+			query: `SELECT ?x WHERE { ?x a ub:Publication }`,
+			sigma: []string{"f(x, rdf:type, ub:Publication)"},
+		},
+		"predicate list": {
+			query: `SELECT ?x ?n WHERE { ?x a ub:GraduateStudent ; ub:name ?n ; }`,
+			sigma: []string{"q(x, n) :-", "f(x, rdf:type, ub:GraduateStudent)", "f(x, ub:name, n)"},
+		},
+		"object list": {
+			query: `SELECT * WHERE { ?x ub:takesCourse ?y, ?z }`,
+			sigma: []string{"q(x, y, z) :-", "f(x, ub:takesCourse, y)", "f(x, ub:takesCourse, z)"},
+		},
+		"literal": {
+			query: `SELECT ?x WHERE { ?x ub:name "University0" }`,
+			sigma: []string{`f(x, ub:name, "University0")`},
+		},
+		"literal as variable name": {
+			query: `SELECT ?x WHERE { ?x ub:name "x" }`,
+			sigma: []string{`f(x, ub:name, "x")`},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rules, err := sparql.Translate(tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, expect := range tc.sigma {
+				if !strings.Contains(rules, expect) {
+					t.Errorf("%s is not found in\n%s", expect, rules)
+				}
+			}
+		})
+	}
+}
+
+func TestTranslateRejects(t *testing.T) {
+	for name, query := range map[string]string{
+		"filter":   `SELECT ?x WHERE { ?x ub:name ?n FILTER (?n = "x") }`,
+		"limit":    `SELECT ?x WHERE { ?x ub:name ?n } LIMIT 10`,
+		"distinct": `SELECT DISTINCT ?x WHERE { ?x ub:name ?n }`,
+		"prefix":   `SELECT ?x WHERE { ?x foo:name ?n }`,
+		"unbound":  `SELECT ?y WHERE { ?x ub:name ?n }`,
+		"empty":    `SELECT * WHERE { }`,
+		"repeated": `SELECT ?x WHERE { ?x ub:memberOf ?x }`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if rules, err := sparql.Translate(query); err == nil {
+				t.Errorf("query is translated\n%s", rules)
+			}
+		})
+	}
+}
+
+func TestTranslateInference(t *testing.T) {
+	for name, query := range map[string]string{
+		"class":      `SELECT ?x WHERE { ?x a ub:Professor }`,
+		"property":   `SELECT ?x WHERE { <http://www.University0.edu> ub:hasAlumnus ?x }`,
+		"member":     `SELECT ?x WHERE { ?x ub:memberOf <http://www.Department0.University0.edu> }`,
+		"transitive": `SELECT ?x WHERE { ?x a ub:ResearchGroup ; ub:subOrganizationOf <http://www.University0.edu> }`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := sparql.Translate(query); !errors.Is(err, sparql.ErrInference) {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestOfficial(t *testing.T) {
+	queries, err := sparql.Official()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(queries) != 14 {
+		t.Fatalf("unexpected number of queries %d", len(queries))
+	}
+
+	answered := map[string]bool{"Query1": true, "Query2": true, "Query3": true, "Query14": true}
+	for _, q := range queries {
+		err := q.Inference()
+		switch {
+		case answered[q.Name] && err != nil:
+			t.Errorf("%s: unexpected error %v", q.Name, err)
+		case !answered[q.Name] && !errors.Is(err, sparql.ErrInference):
+			t.Errorf("%s: inference is not detected", q.Name)
+		}
+	}
+}
+
+// official queries answered without inference match hand-written ones
+func TestOfficialDrift(t *testing.T) {
+	bags := &lubm.Statements{}
+	if err := lubm.NewDataSet(1683234740, 1, nil).Visit(0, bags); err != nil {
+		t.Fatal(err)
+	}
+
+	store := ephemeral.New()
+	for _, bag := range bags.Bags {
+		ephemeral.Add(store, bag)
+	}
+
+	queries, err := sparql.Official()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, id := range map[string]int{"Query2": 2, "Query3": 3, "Query14": 6} {
+		for _, q := range queries {
+			if q.Name != name {
+				continue
+			}
+
+			query, err := lubm.Query(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expect, err := lubm.Eval(store, query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := lubm.Eval(store, q.Sigma())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result.Len() == 0 || result.Len() != expect.Len() {
+				t.Errorf("%s: unexpected %d results, expected %d", name, result.Len(), expect.Len())
+			}
+		}
+	}
+}
@@ -61,6 +61,7 @@ type Student struct {
 	Telephone               string `json:"ub:telephone"`
 	TakesCourse             []IRI  `json:"ub:takesCourse"`
 	UndergraduateDegreeFrom *IRI   `json:"ub:undergraduateDegreeFrom,omitempty"`
+	MastersDegreeFrom       *IRI   `json:"ub:mastersDegreeFrom,omitempty"`
 	Advisor                 *IRI   `json:"ub:advisor,omitempty"`
 	TeachingAssistantOf     *IRI   `json:"ub:teachingAssistantOf,omitempty"`
 }
@@ -0,0 +1,728 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package lubm
+
+import (
+	"math/rand"
+	"strconv"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/spock"
+	"github.com/kshard/xsd"
+)
+
+// ChangeKind is a type of update applied to the dataset
+type ChangeKind int
+
+const (
+	// Student takes one more course of the department
+	Enroll ChangeKind = iota
+	// Student drops one of the courses
+	Drop
+	// GraduateStudent stops taking courses and gains mastersDegreeFrom
+	Graduate
+	// Faculty moves to another department of the university, courses are
+	// handed over to faculty of the former department
+	Move
+	// Department hires new Lecturer
+	Hire
+	// Faculty leaves the university, courses are handed over, graduate
+	// students get another advisor, authorship is removed and publications
+	// without authors are deleted
+	Retire
+	// Faculty publishes new Publication, optionally with GraduateStudents
+	Publish
+)
+
+func (kind ChangeKind) String() string {
+	switch kind {
+	case Enroll:
+		return "enroll"
+	case Drop:
+		return "drop"
+	case Graduate:
+		return "graduate"
+	case Move:
+		return "move"
+	case Hire:
+		return "hire"
+	case Retire:
+		return "retire"
+	case Publish:
+		return "publish"
+	default:
+		return "unknown"
+	}
+}
+
+// relative frequency of changes
+var changes = []struct {
+	kind   ChangeKind
+	weight int
+}{
+	{Enroll, 30},
+	{Drop, 20},
+	{Graduate, 10},
+	{Move, 5},
+	{Hire, 5},
+	{Retire, 5},
+	{Publish, 25},
+}
+
+// Change is a batch of knowledge statements to delete and insert.
+// Deletes are applied before inserts.
+type Change struct {
+	Seq    int
+	Kind   ChangeKind
+	Delete spock.Bag
+	Insert spock.Bag
+}
+
+// Updates is deterministic generator of changes on top of the dataset.
+// It maintains the entity model of generated universities and the
+// reference graph, the state of dataset after all emitted changes.
+//
+// Changes keep invariants of the generated dataset: every course has a
+// teacher, every publication has an author and every graduate student
+// has an advisor.
+type Updates struct {
+	rand            *rand.Rand
+	maxUniversityID int
+	universities    []*university
+	lecturers       map[*department]int
+	publications    map[UID]int // next publication of faculty
+	graph           *Graph
+	seq             int
+}
+
+// NewUpdates builds universities [0, n) exactly as DataSet does for the same
+// seed and maxUniversityID. Changes are derived from the same seed.
+func NewUpdates(seed int64, n, maxUniversityID int) (*Updates, error) {
+	ds := NewDataSet(seed, maxUniversityID, nil)
+
+	updates := &Updates{
+		rand:            rand.New(rand.NewSource(seed)),
+		maxUniversityID: maxUniversityID,
+		lecturers:       map[*department]int{},
+		publications:    map[UID]int{},
+		graph:           NewGraph(),
+	}
+
+	for i := 0; i < n; i++ {
+		university := ds.buildUniversity(i)
+		if err := ds.visit(i, university, graphOf{updates.graph}); err != nil {
+			return nil, err
+		}
+
+		// publications are numbered within department
+		for _, dept := range university.departments {
+			for _, faculty := range dept.faculties {
+				if faculty.Type == "ub:Lecturer" {
+					updates.lecturers[dept]++
+				}
+				updates.publications[faculty.ID] = len(dept.publications)
+			}
+		}
+
+		updates.universities = append(updates.universities, university)
+	}
+
+	return updates, nil
+}
+
+// Snapshot returns knowledge statements of the dataset after emitted changes
+func (updates *Updates) Snapshot() spock.Bag { return updates.graph.Bag() }
+
+// Len returns number of knowledge statements after emitted changes
+func (updates *Updates) Len() int { return updates.graph.Len() }
+
+// Expected returns answers of Queries() after emitted changes, the answer
+// #i corresponds to the query #i+1. Answers are derived from the entity
+// model, independently of the query engine and of the reference graph.
+func (updates *Updates) Expected() []*Result {
+	m := modelOf(updates.universities)
+
+	q1 := &Result{Vars: []string{"x"}}
+	for _, x := range m.students {
+		if x.Type == "ub:GraduateStudent" && contains(x.TakesCourse, defaultCourse) {
+			q1.add(x.ID)
+		}
+	}
+
+	q2 := &Result{Vars: []string{"y", "z", "x"}}
+	for _, x := range m.students {
+		if x.Type != "ub:GraduateStudent" || x.UndergraduateDegreeFrom == nil {
+			continue
+		}
+		y := *x.UndergraduateDegreeFrom
+		if z, has := m.departments[x.MemberOf]; has && m.universities[y] && z.SubOrganizationOf == y {
+			q2.add(y, z.ID, x.ID)
+		}
+	}
+
+	q3 := &Result{Vars: []string{"x"}}
+	for _, x := range m.publications {
+		if contains(x.PublicationAuthor, defaultFaculty) {
+			q3.add(x.ID)
+		}
+	}
+
+	q4 := &Result{Vars: []string{"x", "name", "email", "phone"}}
+	for _, x := range m.faculties {
+		if x.WorksFor == defaultDepartment {
+			q4.add(x.ID, x.Name, x.EmailAddress, x.Telephone)
+		}
+	}
+
+	q5 := &Result{Vars: []string{"x"}}
+	q6 := &Result{Vars: []string{"x"}}
+	for _, x := range m.students {
+		if x.Type == "ub:UndergraduateStudent" {
+			q6.add(x.ID)
+			if x.MemberOf == defaultDepartment {
+				q5.add(x.ID)
+			}
+		}
+	}
+
+	q7 := &Result{Vars: []string{"x", "y"}}
+	if t, has := m.faculties[defaultFaculty]; has {
+		for _, y := range set(t.TeacherOf) {
+			if m.courses[y] != "ub:Course" {
+				continue
+			}
+			for _, x := range m.students {
+				if x.Type == "ub:UndergraduateStudent" && contains(x.TakesCourse, y) {
+					q7.add(x.ID, y)
+				}
+			}
+		}
+	}
+
+	q8 := &Result{Vars: []string{"y", "x", "email"}}
+	for _, x := range m.students {
+		if y, has := m.departments[x.MemberOf]; has && y.SubOrganizationOf == defaultUniversity {
+			q8.add(y.ID, x.ID, x.EmailAddress)
+		}
+	}
+
+	q9 := &Result{Vars: []string{"x"}}
+	for _, x := range m.students {
+		if x.Advisor == nil {
+			continue
+		}
+		if y, has := m.faculties[*x.Advisor]; has {
+			for _, z := range set(y.TeacherOf) {
+				if contains(x.TakesCourse, z) {
+					q9.add(x.ID)
+				}
+			}
+		}
+	}
+
+	return []*Result{q1, q2, q3, q4, q5, q6, q7, q8, q9}
+}
+
+// model indexes entities of universities
+type model struct {
+	universities map[IRI]bool
+	departments  map[IRI]*Department
+	faculties    map[IRI]*Faculty
+	courses      map[IRI]UID // type of course
+	students     []*Student
+	publications []*Publication
+}
+
+func modelOf(universities []*university) *model {
+	m := &model{
+		universities: map[IRI]bool{},
+		departments:  map[IRI]*Department{},
+		faculties:    map[IRI]*Faculty{},
+		courses:      map[IRI]UID{},
+	}
+
+	for _, u := range universities {
+		m.universities[IRI(u.ID)] = true
+		for _, d := range u.departments {
+			m.departments[IRI(d.ID)] = d.Department
+			for _, x := range d.faculties {
+				m.faculties[IRI(x.ID)] = x
+			}
+			for _, x := range append(d.courses, d.graduateCourses...) {
+				m.courses[IRI(x.ID)] = x.Type
+			}
+			m.students = append(m.students, d.undergraduateStudents...)
+			m.students = append(m.students, d.graduateStudents...)
+			m.students = append(m.students, d.alumni...)
+			m.publications = append(m.publications, d.publications...)
+		}
+	}
+
+	return m
+}
+
+// add tuple of IRIs and literals to the result
+func (result *Result) add(values ...any) {
+	row := make([]xsd.Value, len(values))
+	for i, v := range values {
+		switch v := v.(type) {
+		case UID:
+			row[i] = xsd.ToAnyURI(curie.IRI(v))
+		case IRI:
+			row[i] = xsd.ToAnyURI(curie.IRI(v))
+		case string:
+			row[i] = xsd.String(v)
+		}
+	}
+	result.Rows = append(result.Rows, row)
+}
+
+// Next generates the change and applies it to the reference graph
+func (updates *Updates) Next() (Change, error) {
+	for {
+		kind := updates.kind()
+
+		var (
+			ok  bool
+			err error
+			tx  = &transaction{}
+		)
+
+		switch kind {
+		case Enroll:
+			ok, err = updates.enroll(tx)
+		case Drop:
+			ok, err = updates.drop(tx)
+		case Graduate:
+			ok, err = updates.graduate(tx)
+		case Move:
+			ok, err = updates.move(tx)
+		case Hire:
+			ok, err = updates.hire(tx)
+		case Retire:
+			ok, err = updates.retire(tx)
+		case Publish:
+			ok, err = updates.publish(tx)
+		}
+
+		if err != nil {
+			return Change{}, err
+		}
+
+		// the change is not applicable to current state, e.g. no candidates
+		if !ok {
+			continue
+		}
+
+		change, err := tx.commit(updates.graph)
+		if err != nil {
+			return Change{}, err
+		}
+
+		change.Seq = updates.seq
+		change.Kind = kind
+		updates.seq++
+
+		return change, nil
+	}
+}
+
+func (updates *Updates) kind() ChangeKind {
+	total := 0
+	for _, c := range changes {
+		total += c.weight
+	}
+
+	x := updates.rand.Intn(total)
+	for _, c := range changes {
+		if x < c.weight {
+			return c.kind
+		}
+		x -= c.weight
+	}
+
+	return changes[len(changes)-1].kind
+}
+
+func (updates *Updates) department() (*university, *department) {
+	university := updates.universities[updates.rand.Intn(len(updates.universities))]
+	dept := university.departments[updates.rand.Intn(len(university.departments))]
+	return university, dept
+}
+
+func (updates *Updates) student(dept *department) (*Student, []*Course) {
+	n := len(dept.undergraduateStudents) + len(dept.graduateStudents)
+	if n == 0 {
+		return nil, nil
+	}
+
+	id := updates.rand.Intn(n)
+	if id < len(dept.undergraduateStudents) {
+		return dept.undergraduateStudents[id], dept.courses
+	}
+
+	return dept.graduateStudents[id-len(dept.undergraduateStudents)], dept.graduateCourses
+}
+
+// faculty who is not the head of the department
+func (updates *Updates) faculty(dept *department) (int, *Faculty) {
+	candidates := []int{}
+	for i, faculty := range dept.faculties {
+		if faculty.HeadOf == nil {
+			candidates = append(candidates, i)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return -1, nil
+	}
+
+	i := candidates[updates.rand.Intn(len(candidates))]
+	return i, dept.faculties[i]
+}
+
+func (updates *Updates) enroll(tx *transaction) (bool, error) {
+	_, dept := updates.department()
+	student, courses := updates.student(dept)
+	if student == nil {
+		return false, nil
+	}
+
+	candidates := []IRI{}
+	for _, course := range courses {
+		if !contains(student.TakesCourse, IRI(course.ID)) {
+			candidates = append(candidates, IRI(course.ID))
+		}
+	}
+
+	if len(candidates) == 0 {
+		return false, nil
+	}
+
+	return true, tx.update(student, func() {
+		student.TakesCourse = append(student.TakesCourse, candidates[updates.rand.Intn(len(candidates))])
+	})
+}
+
+func (updates *Updates) drop(tx *transaction) (bool, error) {
+	_, dept := updates.department()
+	student, _ := updates.student(dept)
+	if student == nil || len(student.TakesCourse) == 0 {
+		return false, nil
+	}
+
+	return true, tx.update(student, func() {
+		student.TakesCourse = remove(student.TakesCourse, student.TakesCourse[updates.rand.Intn(len(student.TakesCourse))])
+	})
+}
+
+func (updates *Updates) graduate(tx *transaction) (bool, error) {
+	university, dept := updates.department()
+	if len(dept.graduateStudents) == 0 {
+		return false, nil
+	}
+
+	i := updates.rand.Intn(len(dept.graduateStudents))
+	student := dept.graduateStudents[i]
+
+	// graduated students are not selected for further changes
+	dept.graduateStudents = append(dept.graduateStudents[:i:i], dept.graduateStudents[i+1:]...)
+	dept.alumni = append(dept.alumni, student)
+
+	return true, tx.update(student, func() {
+		degree := IRI(university.ID)
+		student.MastersDegreeFrom = &degree
+		student.TakesCourse = []IRI{}
+		student.TeachingAssistantOf = nil
+	})
+}
+
+func (updates *Updates) move(tx *transaction) (bool, error) {
+	university, dept := updates.department()
+	if len(university.departments) < 2 {
+		return false, nil
+	}
+
+	i, faculty := updates.faculty(dept)
+	if faculty == nil {
+		return false, nil
+	}
+
+	target := dept
+	for target == dept {
+		target = university.departments[updates.rand.Intn(len(university.departments))]
+	}
+
+	dept.faculties = append(dept.faculties[:i:i], dept.faculties[i+1:]...)
+	target.faculties = append(target.faculties, faculty)
+
+	// courses of the faculty stay with the former department
+	if err := updates.handover(tx, dept, faculty); err != nil {
+		return false, err
+	}
+
+	return true, tx.update(faculty, func() {
+		faculty.WorksFor = IRI(target.ID)
+		faculty.TeacherOf = []IRI{}
+	})
+}
+
+// hands courses of faculty over to another faculty of the department,
+// the head of department is never moved or retired, it is always there.
+func (updates *Updates) handover(tx *transaction, dept *department, faculty *Faculty) error {
+	if len(faculty.TeacherOf) == 0 {
+		return nil
+	}
+
+	successor := dept.faculties[updates.rand.Intn(len(dept.faculties))]
+	return tx.update(successor, func() {
+		successor.TeacherOf = append(successor.TeacherOf, faculty.TeacherOf...)
+	})
+}
+
+// professor of the department
+func (updates *Updates) professor(dept *department) *Faculty {
+	candidates := []*Faculty{}
+	for _, faculty := range dept.faculties {
+		if faculty.Type != "ub:Lecturer" {
+			candidates = append(candidates, faculty)
+		}
+	}
+
+	return candidates[updates.rand.Intn(len(candidates))]
+}
+
+func (updates *Updates) hire(tx *transaction) (bool, error) {
+	_, dept := updates.department()
+
+	faculty := newLecturer(updates.lecturers[dept], dept.Department, updates.rand)
+	faculty.UndergraduateDegreeFrom = updates.degreeFromUniversity()
+	faculty.MastersDegreeFrom = updates.degreeFromUniversity()
+	faculty.DoctoralDegreeFrom = updates.degreeFromUniversity()
+
+	updates.lecturers[dept]++
+	dept.faculties = append(dept.faculties, faculty)
+
+	return true, tx.insert(faculty)
+}
+
+func (updates *Updates) retire(tx *transaction) (bool, error) {
+	university, dept := updates.department()
+	i, faculty := updates.faculty(dept)
+	if faculty == nil {
+		return false, nil
+	}
+
+	dept.faculties = append(dept.faculties[:i:i], dept.faculties[i+1:]...)
+
+	if err := updates.handover(tx, dept, faculty); err != nil {
+		return false, err
+	}
+
+	if err := tx.delete(faculty); err != nil {
+		return false, err
+	}
+
+	// advisors and co-authors are within the university, faculty who moved
+	// keeps relations with the former department
+	for _, d := range university.departments {
+		for _, students := range [][]*Student{d.undergraduateStudents, d.graduateStudents, d.alumni} {
+			for _, student := range students {
+				if student.Advisor == nil || *student.Advisor != IRI(faculty.ID) {
+					continue
+				}
+
+				err := tx.update(student, func() {
+					student.Advisor = nil
+					if student.Type == "ub:GraduateStudent" {
+						advisor := IRI(updates.professor(d).ID)
+						student.Advisor = &advisor
+					}
+				})
+				if err != nil {
+					return false, err
+				}
+			}
+		}
+
+		publications := d.publications[:0:0]
+		for _, publication := range d.publications {
+			if !contains(publication.PublicationAuthor, IRI(faculty.ID)) {
+				publications = append(publications, publication)
+				continue
+			}
+
+			if len(publication.PublicationAuthor) == 1 {
+				if err := tx.delete(publication); err != nil {
+					return false, err
+				}
+				continue
+			}
+
+			err := tx.update(publication, func() {
+				publication.PublicationAuthor = remove(publication.PublicationAuthor, IRI(faculty.ID))
+			})
+			if err != nil {
+				return false, err
+			}
+			publications = append(publications, publication)
+		}
+		d.publications = publications
+	}
+
+	return true, nil
+}
+
+func (updates *Updates) publish(tx *transaction) (bool, error) {
+	_, dept := updates.department()
+	if len(dept.faculties) == 0 {
+		return false, nil
+	}
+
+	// publication is identified by faculty, who might come from another
+	// department
+	faculty := dept.faculties[updates.rand.Intn(len(dept.faculties))]
+	publication := newPublication(updates.publications[faculty.ID], faculty)
+	updates.publications[faculty.ID]++
+
+	// 0~2 GraduateStudents co-author the Publication
+	if len(dept.graduateStudents) > 0 {
+		for i := 0; i < updates.rand.Intn(3); i++ {
+			student := dept.graduateStudents[updates.rand.Intn(len(dept.graduateStudents))]
+			if !contains(publication.PublicationAuthor, IRI(student.ID)) {
+				publication.PublicationAuthor = append(publication.PublicationAuthor, IRI(student.ID))
+			}
+		}
+	}
+
+	dept.publications = append(dept.publications, publication)
+
+	return true, tx.insert(publication)
+}
+
+func (updates *Updates) degreeFromUniversity() *IRI {
+	id := updates.rand.Intn(updates.maxUniversityID)
+	iri := IRI("edu:University" + strconv.Itoa(id))
+	return &iri
+}
+
+// graphOf is visitor that adds knowledge statements of entities to the graph
+type graphOf struct{ *Graph }
+
+func (g graphOf) add(x any) error {
+	bag, err := Encode(x)
+	if err != nil {
+		return err
+	}
+	g.Add(bag)
+	return nil
+}
+
+func (g graphOf) University(x *University) error       { return g.add(x) }
+func (g graphOf) Department(x *Department) error       { return g.add(x) }
+func (g graphOf) Faculty(x *Faculty) error             { return g.add(x) }
+func (g graphOf) Student(x *Student) error             { return g.add(x) }
+func (g graphOf) Course(x *Course) error               { return g.add(x) }
+func (g graphOf) Publication(x *Publication) error     { return g.add(x) }
+func (g graphOf) ResearchGroup(x *ResearchGroup) error { return g.add(x) }
+
+//
+// transaction accumulates knowledge statements affected by the change
+//
+
+type transaction struct {
+	deletes spock.Bag
+	inserts spock.Bag
+}
+
+func (tx *transaction) insert(obj any) error {
+	bag, err := Encode(obj)
+	if err != nil {
+		return err
+	}
+
+	tx.inserts = append(tx.inserts, bag...)
+	return nil
+}
+
+func (tx *transaction) delete(obj any) error {
+	bag, err := Encode(obj)
+	if err != nil {
+		return err
+	}
+
+	tx.deletes = append(tx.deletes, bag...)
+	return nil
+}
+
+// update encodes entity before and after the mutation
+func (tx *transaction) update(obj any, mutate func()) error {
+	if err := tx.delete(obj); err != nil {
+		return err
+	}
+
+	mutate()
+
+	return tx.insert(obj)
+}
+
+// commit applies the transaction to the graph, statements both deleted
+// and inserted are not part of the change.
+func (tx *transaction) commit(graph *Graph) (Change, error) {
+	deleted, inserted := NewGraph(), NewGraph()
+	deleted.Add(tx.deletes)
+	inserted.Add(tx.inserts)
+
+	change := Change{}
+	for _, x := range deleted.Bag() {
+		if !inserted.Has(x) && graph.Has(x) {
+			change.Delete = append(change.Delete, x)
+		}
+	}
+	for _, x := range inserted.Bag() {
+		if !deleted.Has(x) && !graph.Has(x) {
+			change.Insert = append(change.Insert, x)
+		}
+	}
+
+	graph.Remove(change.Delete)
+	graph.Add(change.Insert)
+
+	return change, nil
+}
+
+func contains(seq []IRI, iri IRI) bool {
+	for _, x := range seq {
+		if x == iri {
+			return true
+		}
+	}
+	return false
+}
+
+// set of IRIs, knowledge statements are never repeated
+func set(seq []IRI) []IRI {
+	out := make([]IRI, 0, len(seq))
+	for _, x := range seq {
+		if !contains(out, x) {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+func remove(seq []IRI, iri IRI) []IRI {
+	out := make([]IRI, 0, len(seq))
+	for _, x := range seq {
+		if x != iri {
+			out = append(out, x)
+		}
+	}
+	return out
+}
@@ -0,0 +1,163 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package lubm_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/kshard/lubm"
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/store/ephemeral"
+	"github.com/kshard/xsd"
+)
+
+func TestUpdatesInvariants(t *testing.T) {
+	updates, err := lubm.NewUpdates(1683234740, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := map[lubm.ChangeKind]int{}
+	for i := 0; i < 3000; i++ {
+		change, err := updates.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		kinds[change.Kind]++
+	}
+
+	for _, kind := range []lubm.ChangeKind{lubm.Move, lubm.Retire, lubm.Publish} {
+		if kinds[kind] == 0 {
+			t.Fatalf("no %s changes", kind)
+		}
+	}
+
+	types := map[string]string{}
+	taught := map[string]bool{}
+	authored := map[string]bool{}
+	advised := map[string]bool{}
+	for _, x := range updates.Snapshot() {
+		s := x.S.String()
+		switch x.P.String() {
+		case "rdf:type":
+			types[s] = x.O.(xsd.AnyURI).String()
+		case "ub:teacherOf":
+			taught[x.O.(xsd.AnyURI).String()] = true
+		case "ub:publicationAuthor":
+			authored[s] = true
+		case "ub:advisor":
+			advised[s] = true
+		}
+	}
+
+	for s, kind := range types {
+		switch kind {
+		case "ub:Course", "ub:GraduateCourse":
+			if !taught[s] {
+				t.Errorf("course %s has no teacher", s)
+			}
+		case "ub:Publication":
+			if !authored[s] {
+				t.Errorf("publication %s has no author", s)
+			}
+		case "ub:GraduateStudent":
+			if !advised[s] {
+				t.Errorf("graduate student %s has no advisor", s)
+			}
+		}
+	}
+}
+
+// answers derived from the entity model match the engine over the snapshot
+func TestUpdatesExpected(t *testing.T) {
+	updates, err := lubm.NewUpdates(1683234740, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, changes := range []int{0, 500} {
+		for i := 0; i < changes; i++ {
+			if _, err := updates.Next(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		store := ephemeral.New()
+		ephemeral.Add(store, updates.Snapshot())
+
+		for i, expected := range updates.Expected() {
+			result, err := lubm.Eval(store, lubm.Queries()[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			a, b := tuplesOf(result), tuplesOf(expected)
+			if strings.Join(a, "\n") != strings.Join(b, "\n") {
+				t.Errorf("Query%d after %d changes: %d results, expected %d", i+1, changes, len(a), len(b))
+			}
+		}
+	}
+}
+
+func tuplesOf(result *lubm.Result) []string {
+	seq := make([]string, result.Len())
+	for i, row := range result.Rows {
+		seq[i] = fmt.Sprint(row)
+	}
+	sort.Strings(seq)
+	return seq
+}
+
+func TestMutable(t *testing.T) {
+	updates, err := lubm.NewUpdates(1683234740, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := updates.Snapshot()
+	store := ephemeral.New()
+	ephemeral.Add(store, snapshot)
+	mutable := lubm.NewMutable(store)
+
+	q, err := lubm.Query(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eval := func() int {
+		t.Helper()
+		result, err := mutable.EvalContext(context.Background(), q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Len()
+	}
+
+	students := eval()
+	removed := spock.Bag{}
+	for _, x := range snapshot {
+		if o, ok := x.O.(xsd.AnyURI); ok && x.P.String() == "rdf:type" && o.String() == "ub:UndergraduateStudent" {
+			removed = append(removed, x)
+		}
+	}
+
+	mutable.Remove(removed[:10])
+	if n := eval(); n != students-10 || mutable.Len() != len(snapshot)-10 {
+		t.Errorf("removed statements are visible, %d of %d students", n, students)
+	}
+
+	mutable.Add(removed[:10])
+	if n := eval(); n != students || mutable.Len() != len(snapshot) {
+		t.Errorf("added statements are not visible, %d of %d students", n, students)
+	}
+}
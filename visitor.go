@@ -0,0 +1,238 @@
+//
+// Copyright (C) 2023 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/lubm
+//
+
+package lubm
+
+import "github.com/kshard/spock"
+
+// Visitor receives entities of the university once they are finalized,
+// in the same order as they are encoded into knowledge statements.
+// Entities are passed by pointer and may be retained after the call, e.g.
+// by Entities. DataSet does not modify entities once they are visited but
+// Updates keeps the visited model and modifies it while emitting changes,
+// visitors requiring a snapshot encode or copy entities when visited.
+//
+// Visitors buffering entities might implement Flush() error, it is called
+// after the university entity and after every department.
+type Visitor interface {
+	University(*University) error
+	Department(*Department) error
+	Faculty(*Faculty) error
+	Student(*Student) error
+	Course(*Course) error
+	Publication(*Publication) error
+	ResearchGroup(*ResearchGroup) error
+}
+
+type flusher interface{ Flush() error }
+
+// Visit generates the university and passes its entities to the visitor.
+// Universities are seeded independently, the visitor receives exactly the
+// entities encoded by Generate.
+func (dataset *DataSet) Visit(universityID int, visitor Visitor) error {
+	return dataset.visit(universityID, dataset.buildUniversity(universityID), visitor)
+}
+
+// visits entities of the university in the order of encoding
+func (dataset *DataSet) visit(universityID int, university *university, visitor Visitor) error {
+	flush := func() error {
+		if f, ok := visitor.(flusher); ok {
+			return f.Flush()
+		}
+		return nil
+	}
+
+	dataset.progress.university(universityID)
+	counted := &counter{Visitor: visitor, progress: &dataset.progress}
+
+	if err := counted.University(university.University); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	for _, dept := range university.departments {
+		if err := dept.visit(counted); err != nil {
+			return err
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+		dataset.progress.department()
+	}
+
+	dataset.progress.done()
+	return nil
+}
+
+// visits entities of the department in the order of encoding
+func (dept *department) visit(visitor Visitor) error {
+	if err := visitor.Department(dept.Department); err != nil {
+		return err
+	}
+
+	for _, x := range dept.faculties {
+		if err := visitor.Faculty(x); err != nil {
+			return err
+		}
+	}
+
+	for _, x := range dept.undergraduateStudents {
+		if err := visitor.Student(x); err != nil {
+			return err
+		}
+	}
+
+	for _, x := range dept.graduateStudents {
+		if err := visitor.Student(x); err != nil {
+			return err
+		}
+	}
+
+	for _, x := range dept.courses {
+		if err := visitor.Course(x); err != nil {
+			return err
+		}
+	}
+
+	for _, x := range dept.graduateCourses {
+		if err := visitor.Course(x); err != nil {
+			return err
+		}
+	}
+
+	for _, x := range dept.publications {
+		if err := visitor.Publication(x); err != nil {
+			return err
+		}
+	}
+
+	for _, x := range dept.researchGroups {
+		if err := visitor.ResearchGroup(x); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// counter accounts knowledge statements of visited entities in progress
+type counter struct {
+	Visitor
+	progress *tracker
+}
+
+func (v *counter) University(x *University) error {
+	v.progress.Triples += triples(x)
+	return v.Visitor.University(x)
+}
+
+func (v *counter) Department(x *Department) error {
+	v.progress.Triples += triples(x)
+	return v.Visitor.Department(x)
+}
+
+func (v *counter) Faculty(x *Faculty) error {
+	v.progress.Triples += triples(x)
+	return v.Visitor.Faculty(x)
+}
+
+func (v *counter) Student(x *Student) error {
+	v.progress.Triples += triples(x)
+	return v.Visitor.Student(x)
+}
+
+func (v *counter) Course(x *Course) error {
+	v.progress.Triples += triples(x)
+	return v.Visitor.Course(x)
+}
+
+func (v *counter) Publication(x *Publication) error {
+	v.progress.Triples += triples(x)
+	return v.Visitor.Publication(x)
+}
+
+func (v *counter) ResearchGroup(x *ResearchGroup) error {
+	v.progress.Triples += triples(x)
+	return v.Visitor.ResearchGroup(x)
+}
+
+// rdf is visitor that encodes entities into knowledge statements, the bag
+// of statements is written per department.
+type rdf struct {
+	dataset *DataSet
+	pending []any
+}
+
+func (v *rdf) add(x any) error {
+	v.pending = append(v.pending, x)
+	return nil
+}
+
+func (v *rdf) University(x *University) error       { return v.add(x) }
+func (v *rdf) Department(x *Department) error       { return v.add(x) }
+func (v *rdf) Faculty(x *Faculty) error             { return v.add(x) }
+func (v *rdf) Student(x *Student) error             { return v.add(x) }
+func (v *rdf) Course(x *Course) error               { return v.add(x) }
+func (v *rdf) Publication(x *Publication) error     { return v.add(x) }
+func (v *rdf) ResearchGroup(x *ResearchGroup) error { return v.add(x) }
+
+func (v *rdf) Flush() error {
+	if len(v.pending) == 0 {
+		return nil
+	}
+
+	err := v.dataset.Write(v.pending)
+	v.pending = v.pending[:0]
+	return err
+}
+
+// Statements is visitor that collects knowledge statements of entities, the
+// bag of statements is collected per department, same as Generate writes.
+type Statements struct {
+	Bags    []spock.Bag
+	pending []any
+}
+
+func (v *Statements) add(x any) error {
+	v.pending = append(v.pending, x)
+	return nil
+}
+
+func (v *Statements) University(x *University) error       { return v.add(x) }
+func (v *Statements) Department(x *Department) error       { return v.add(x) }
+func (v *Statements) Faculty(x *Faculty) error             { return v.add(x) }
+func (v *Statements) Student(x *Student) error             { return v.add(x) }
+func (v *Statements) Course(x *Course) error               { return v.add(x) }
+func (v *Statements) Publication(x *Publication) error     { return v.add(x) }
+func (v *Statements) ResearchGroup(x *ResearchGroup) error { return v.add(x) }
+
+func (v *Statements) Flush() error {
+	if len(v.pending) == 0 {
+		return nil
+	}
+
+	bag, err := Encode(v.pending)
+	if err != nil {
+		return err
+	}
+
+	v.Bags = append(v.Bags, bag)
+	v.pending = v.pending[:0]
+	return nil
+}
+
+// Len is number of collected knowledge statements
+func (v *Statements) Len() int {
+	n := 0
+	for _, bag := range v.Bags {
+		n += len(bag)
+	}
+	return n
+}